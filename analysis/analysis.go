@@ -0,0 +1,184 @@
+// Package analysis measures the closed-loop response of pacers to
+// canonical excitations.
+package analysis
+
+import (
+	"math"
+
+	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/scenario"
+	"github.com/mknyszek/pacer-model/simulation"
+)
+
+const (
+	// tailCycles is the number of cycles at the end of a response
+	// that are considered to be its steady state.
+	tailCycles = 10
+
+	// settlingBand is the band around the final value, as a fraction
+	// of the size of the response, that the response settles into.
+	settlingBand = 0.02
+)
+
+// Response is the response of a pacer's R value to an excitation.
+type Response struct {
+	Excitation string `json:"excitation"`
+	Onset      int    `json:"onset"`
+	Impulse    bool   `json:"impulse"`
+
+	// R is Result.R for each cycle, and Ref is the alloc/scan ratio
+	// that the pacer should have used at the target utilization, as
+	// measured from the scenario.
+	R   []float64 `json:"r"`
+	Ref []float64 `json:"ref"`
+}
+
+// Metrics are the standard metrics for a step or impulse response.
+// All times are in GC cycles since the onset of the excitation, or
+// -1 if the response never got there.
+type Metrics struct {
+	// RiseTime is the time for a step response to go from 10% to 90%
+	// of the way to its final value. For an impulse response, or a step
+	// that doesn't change the measured alloc/scan ratio, it's the time
+	// to the peak of the response.
+	RiseTime float64 `json:"rise_time"`
+
+	// SettlingTime is the time after which the response stays within
+	// 2% of its final value, relative to the size of the step, or to
+	// the final value for an impulse.
+	SettlingTime float64 `json:"settling_time"`
+
+	// Overshoot is how far a step response went past its final value,
+	// as a percentage of the size of the step. For an impulse response,
+	// it's the peak deviation from the final value, as a percentage of
+	// the final value.
+	Overshoot float64 `json:"overshoot"`
+
+	// SteadyStateError is the difference between the final value of
+	// the response and the final measured alloc/scan ratio.
+	SteadyStateError float64 `json:"steady_state_error"`
+
+	// OscillationPeriod is the mean period of oscillation around the
+	// final value after the onset, or zero if the response doesn't
+	// oscillate.
+	OscillationPeriod float64 `json:"oscillation_period"`
+}
+
+// Excite runs the named simulator with the given controller and
+// configuration against the named excitation. ctrl and cfg are
+// optional, as for simulation.NewSimulator.
+func Excite(sim string, ctrl controller.Controller, cfg *simulation.SimulatorConfig, excitation string) (Response, error) {
+	ex, err := scenario.Excite(excitation)
+	if err != nil {
+		return Response{}, err
+	}
+	s, err := simulation.NewSimulator(sim, &ex.Execution, ctrl, cfg)
+	if err != nil {
+		return Response{}, err
+	}
+	resp := Response{
+		Excitation: excitation,
+		Onset:      ex.Onset,
+		Impulse:    ex.Impulse,
+	}
+	for i := range ex.Cycles {
+		c := &ex.Cycles[i]
+		r := s.Step(c)
+		u := r.TargetGCUtilization
+		resp.R = append(resp.R, r.R)
+		resp.Ref = append(resp.Ref, c.AllocRate*(1-u)/(c.ScanRate*u))
+	}
+	return resp, nil
+}
+
+// Metrics computes the response's metrics.
+func (r *Response) Metrics() Metrics {
+	y := r.R[r.Onset:]
+	initial := y[0]
+	final := tailMean(r.R)
+	m := Metrics{
+		SteadyStateError: final - tailMean(r.Ref),
+		RiseTime:         -1,
+	}
+
+	// size is the size of the response, which tolerances
+	// are relative to.
+	//
+	// A step that doesn't change the measured alloc/scan ratio, like a
+	// step in the heap target, is a disturbance that R should reject,
+	// so it's measured like an impulse.
+	var size float64
+	refBefore, refAfter := r.Ref[r.Onset-1], tailMean(r.Ref)
+	if r.Impulse || math.Abs(refAfter-refBefore) <= 1e-9*math.Abs(refBefore) {
+		size = math.Abs(final)
+		var peak float64
+		for i, v := range y {
+			if d := math.Abs(v - final); d > peak {
+				peak = d
+				m.RiseTime = float64(i)
+			}
+		}
+		if size != 0 {
+			m.Overshoot = peak / size * 100
+		}
+	} else {
+		size = math.Abs(final - initial)
+		if size != 0 {
+			t10, t90 := -1, -1
+			for i, v := range y {
+				frac := (v - initial) / (final - initial)
+				if t10 < 0 && frac >= 0.1 {
+					t10 = i
+				}
+				if t90 < 0 && frac >= 0.9 {
+					t90 = i
+				}
+				if over := (frac - 1) * 100; over > m.Overshoot {
+					m.Overshoot = over
+				}
+			}
+			if t10 >= 0 && t90 >= 0 {
+				m.RiseTime = float64(t90 - t10)
+			}
+		}
+	}
+
+	// Find when the response last left the settling band, and
+	// count how often it crossed the final value while outside it.
+	band := settlingBand * size
+	var crossings []int
+	sign := 0
+	for i, v := range y {
+		d := v - final
+		if math.Abs(d) <= band {
+			continue
+		}
+		m.SettlingTime = float64(i + 1)
+		s := 1
+		if d < 0 {
+			s = -1
+		}
+		if sign != 0 && s != sign {
+			crossings = append(crossings, i)
+		}
+		sign = s
+	}
+	if len(crossings) >= 2 {
+		// Crossings are half a period apart.
+		span := crossings[len(crossings)-1] - crossings[0]
+		m.OscillationPeriod = 2 * float64(span) / float64(len(crossings)-1)
+	}
+	return m
+}
+
+func tailMean(v []float64) float64 {
+	n := tailCycles
+	if n > len(v) {
+		n = len(v)
+	}
+	var sum float64
+	for _, x := range v[len(v)-n:] {
+		sum += x
+	}
+	return sum / float64(n)
+}
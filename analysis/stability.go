@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"math"
+
+	"github.com/mknyszek/pacer-model/simulation"
+)
+
+// Stability classifies the long-term behavior of a pacer in a scenario.
+type Stability int
+
+const (
+	Converged Stability = iota
+	Oscillating
+	Diverged
+)
+
+func (s Stability) String() string {
+	switch s {
+	case Converged:
+		return "converged"
+	case Oscillating:
+		return "oscillating"
+	case Diverged:
+		return "diverged"
+	}
+	return "unknown"
+}
+
+const (
+	// oscillationThreshold is the peak-to-peak amplitude, relative to
+	// the mean, above which R or the peak heap is considered to be
+	// oscillating.
+	oscillationThreshold = 0.05
+
+	// divergenceGrowth is how much the envelope of oscillations has to
+	// grow over divergencePeriods periods to be considered diverging.
+	divergenceGrowth  = 1.5
+	divergencePeriods = 3
+
+	// divergenceOvershoot is the peak heap overshoot, as a fraction of
+	// the heap goal, past which the pacer has lost control of the heap.
+	divergenceOvershoot = 1.0
+)
+
+// Classify classifies a pacer's behavior from the tail of its results,
+// which is the last half of the cycles. The results should come from a
+// scenario whose inputs are steady in that tail.
+func Classify(results []simulation.Result) Stability {
+	tail := results[len(results)/2:]
+	r := make([]float64, len(tail))
+	peak := make([]float64, len(tail))
+	for i := range tail {
+		r[i] = tail[i].R
+		peak[i] = float64(tail[i].PeakBytes)
+		if math.IsNaN(r[i]) || math.IsInf(r[i], 0) {
+			return Diverged
+		}
+		if tail[i].GoalBytes > 0 && peak[i] > (1+divergenceOvershoot)*float64(tail[i].GoalBytes) {
+			return Diverged
+		}
+	}
+	half := len(tail) / 2
+	stability := Converged
+	for _, v := range [][]float64{r, peak} {
+		if amplitude(v[half:]) > oscillationThreshold {
+			if growing(swings(v)) {
+				return Diverged
+			}
+			stability = Oscillating
+		}
+	}
+	return stability
+}
+
+// swings returns the largest deviation of v from its mean in each complete
+// swing to one side of the mean, that is, between two mean crossings.
+func swings(v []float64) []float64 {
+	var mean float64
+	for _, x := range v {
+		mean += x
+	}
+	mean /= float64(len(v))
+	var s []float64
+	var dev float64
+	started := false
+	for i := 1; i < len(v); i++ {
+		if (v[i-1] < mean) != (v[i] < mean) {
+			if started {
+				s = append(s, dev)
+			}
+			started = true
+			dev = 0
+		}
+		dev = math.Max(dev, math.Abs(v[i]-mean))
+	}
+	return s
+}
+
+// growing reports whether the swings s, two of which make a period, grow
+// on both sides of the mean by divergenceGrowth over divergencePeriods
+// periods without shrinking in any of them. An oscillation that's stuck
+// at a bound on one side, like R at its clamp, is bounded.
+func growing(s []float64) bool {
+	n := 2 * divergencePeriods
+	if len(s) < n+2 {
+		return false
+	}
+	s = s[len(s)-n-2:]
+	for i := 2; i < len(s); i++ {
+		if s[i] < s[i-2] {
+			return false
+		}
+	}
+	return s[n] > divergenceGrowth*s[0] && s[n+1] > divergenceGrowth*s[1]
+}
+
+// amplitude returns the peak-to-peak amplitude of v relative to its mean.
+func amplitude(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	lo, hi, mean := v[0], v[0], 0.0
+	for _, x := range v {
+		lo = math.Min(lo, x)
+		hi = math.Max(hi, x)
+		mean += x
+	}
+	mean /= float64(len(v))
+	if mean == 0 {
+		return hi - lo
+	}
+	return (hi - lo) / math.Abs(mean)
+}
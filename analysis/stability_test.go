@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/scenario"
+	"github.com/mknyszek/pacer-model/simulation"
+)
+
+// synthetic returns results whose R follows r for n cycles.
+func synthetic(n int, r func(i int) float64) []simulation.Result {
+	res := make([]simulation.Result, n)
+	for i := range res {
+		res[i] = simulation.Result{R: r(i), GoalBytes: 8 << 20, PeakBytes: 8 << 20}
+	}
+	return res
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		results []simulation.Result
+		want    Stability
+	}{
+		{"constant", synthetic(50, func(int) float64 { return 0.2 }), Converged},
+		{"decaying", synthetic(50, func(i int) float64 {
+			return 0.2 + 0.1*math.Pow(0.7, float64(i))*math.Sin(float64(i))
+		}), Converged},
+		// A slow limit cycle, clamped at the bottom, whose period is
+		// longer than half the tail.
+		{"bounded", synthetic(50, func(i int) float64 {
+			return math.Max(0.05, 0.13+0.1*math.Sin(2*math.Pi*float64(i)/15))
+		}), Oscillating},
+		{"growing", synthetic(50, func(i int) float64 {
+			return 1 + 0.01*math.Pow(1.2, float64(i))*math.Sin(2*math.Pi*float64(i)/4)
+		}), Diverged},
+		{"nan", synthetic(50, func(i int) float64 {
+			if i > 40 {
+				return math.NaN()
+			}
+			return 0.2
+		}), Diverged},
+	} {
+		if got := Classify(tc.results); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestClassifyLimitCycle checks that a slow limit cycle of go117's R
+// between its lower bound and about 0.21 isn't classified as diverging.
+func TestClassifyLimitCycle(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("..", "data", "scenarios", "steady.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e scenario.Execution
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := simulation.DefaultControllerConfig("go117")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Kp, cfg.Ti = 0.05, 0.1
+	s, err := simulation.NewSimulator("go117", &e, controller.NewPI(&cfg), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r []simulation.Result
+	for i := range e.Cycles {
+		r = append(r, s.Step(&e.Cycles[i]))
+	}
+	if got := Classify(r); got != Oscillating {
+		t.Errorf("got %s, want %s", got, Oscillating)
+	}
+}
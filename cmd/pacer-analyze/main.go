@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mknyszek/pacer-model/analysis"
+	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/scenario"
+	"github.com/mknyszek/pacer-model/simulation"
+)
+
+var (
+	genJSONFlag    = flag.Bool("json", false, "generate JSON, including each response, instead of a table")
+	excitationFlag = flag.String("excitation", "", "only run the named excitation (default all)")
+	ctrlConfigFlag = flag.String("controller-config", "", "file containing JSON controller configuration, whose \"type\" field selects the controller (optional, default parameters used otherwise)")
+	simConfigFlag  = flag.String("sim-config", "", "file containing JSON simulator configuration (optional, overrides the pacer's default tunables)")
+	listFlag       = flag.Bool("l", false, "list available excitations")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if *listFlag {
+		fmt.Println(strings.Join(scenario.Excitations(), "\n"))
+		return nil
+	}
+	if flag.NArg() != 1 {
+		return fmt.Errorf("expected 1 argument: pacer type")
+	}
+	name := flag.Arg(0)
+
+	simCfg, err := simulation.DefaultConfig(name)
+	if err != nil {
+		return err
+	}
+	if *simConfigFlag != "" {
+		data, err := ioutil.ReadFile(*simConfigFlag)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &simCfg); err != nil {
+			return fmt.Errorf("unmarshalling simulator config: %v", err)
+		}
+	}
+	var ctrlData []byte
+	if *ctrlConfigFlag != "" {
+		ctrlData, err = ioutil.ReadFile(*ctrlConfigFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	excitations := scenario.Excitations()
+	if *excitationFlag != "" {
+		excitations = []string{*excitationFlag}
+	}
+	type result struct {
+		analysis.Response
+		Metrics analysis.Metrics `json:"metrics"`
+	}
+	var results []result
+	for _, ex := range excitations {
+		// Controllers are stateful, so each excitation needs a new one.
+		var ctrl controller.Controller
+		if ctrlData != nil {
+			ctrl, err = controller.New(ctrlData)
+			if err != nil {
+				return fmt.Errorf("unmarshalling controller config: %v", err)
+			}
+		}
+		cfg := simCfg
+		resp, err := analysis.Excite(name, ctrl, &cfg, ex)
+		if err != nil {
+			return err
+		}
+		results = append(results, result{resp, resp.Metrics()})
+	}
+
+	if *genJSONFlag {
+		data, err := json.Marshal(results)
+		if err != nil {
+			return fmt.Errorf("marshalling results: %v", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Excitation\tRise Time\tSettling Time\tOvershoot (%)\tSteady-State Error\tOscillation Period")
+	for _, r := range results {
+		m := &r.Metrics
+		fmt.Fprintf(w, "%s\t%g\t%g\t%f\t%f\t%g\n", r.Excitation, m.RiseTime, m.SettlingTime, m.Overshoot, m.SteadyStateError, m.OscillationPeriod)
+	}
+	return w.Flush()
+}
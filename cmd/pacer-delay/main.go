@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/mknyszek/pacer-model/analysis"
+	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/scenario"
+	"github.com/mknyszek/pacer-model/simulation"
+)
+
+var (
+	maxDelayFlag   = flag.Int("max-delay", 8, "largest measurement delay in cycles")
+	ctrlConfigFlag = flag.String("controller-config", "", "file containing JSON controller configuration, whose \"type\" field selects the controller (optional, default parameters used otherwise)")
+	simConfigFlag  = flag.String("sim-config", "", "file containing JSON simulator configuration (optional, overrides the pacer's default tunables)")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if flag.NArg() != 2 {
+		return fmt.Errorf("expected 2 arguments: pacer type and scenario file")
+	}
+	name := flag.Arg(0)
+
+	simCfg, err := simulation.DefaultConfig(name)
+	if err != nil {
+		return err
+	}
+	if *simConfigFlag != "" {
+		data, err := ioutil.ReadFile(*simConfigFlag)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &simCfg); err != nil {
+			return fmt.Errorf("unmarshalling simulator config: %v", err)
+		}
+	}
+	var ctrlData []byte
+	if *ctrlConfigFlag != "" {
+		ctrlData, err = ioutil.ReadFile(*ctrlConfigFlag)
+		if err != nil {
+			return err
+		}
+	}
+	scnData, err := ioutil.ReadFile(flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	var scn scenario.Execution
+	if err := json.Unmarshal(scnData, &scn); err != nil {
+		return fmt.Errorf("unmarshalling scenario data: %v", err)
+	}
+
+	// Check that the pacer supports a measurement delay before
+	// printing anything.
+	sc := simCfg
+	sc.MeasurementDelay = *maxDelayFlag
+	if _, err := simulation.NewSimulator(name, &scn, nil, &sc); err != nil {
+		return err
+	}
+
+	// Stability is classified from the last half of the cycles, but the
+	// rest is measured over the last quarter, so that it's unaffected
+	// by steps in the middle of the scenario. R Error is the mean
+	// error in R relative to the alloc/scan ratio at the target
+	// utilization, which shows when R got stuck at its bounds.
+	fmt.Println("Delay,Stability,R Min,R Max,R Error")
+	for delay := 0; delay <= *maxDelayFlag; delay++ {
+		// Controllers are stateful, so each run needs a new one.
+		var ctrl controller.Controller
+		if ctrlData != nil {
+			ctrl, err = controller.New(ctrlData)
+			if err != nil {
+				return fmt.Errorf("unmarshalling controller config: %v", err)
+			}
+		}
+		sc := simCfg
+		sc.MeasurementDelay = delay
+		s, err := simulation.NewSimulator(name, &scn, ctrl, &sc)
+		if err != nil {
+			return err
+		}
+		var r []simulation.Result
+		for i := range scn.Cycles {
+			r = append(r, s.Step(&scn.Cycles[i]))
+		}
+		rMin, rMax := math.Inf(1), math.Inf(-1)
+		var rErr float64
+		tail := len(r) - len(r)/4
+		for i := tail; i < len(r); i++ {
+			c := &scn.Cycles[i]
+			u := r[i].TargetGCUtilization
+			ref := c.AllocRate * (1 - u) / (c.ScanRate * u)
+			rMin = math.Min(rMin, r[i].R)
+			rMax = math.Max(rMax, r[i].R)
+			rErr += math.Abs(r[i].R-ref) / ref
+		}
+		rErr /= float64(len(r) - tail)
+		fmt.Printf("%d,%s,%f,%f,%f\n", delay, analysis.Classify(r), rMin, rMax, rErr)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mknyszek/pacer-model/scenario"
+	"github.com/mknyszek/pacer-model/simulation"
+)
+
+// printGCTrace prints results in the format of the runtime's GODEBUG=gctrace=1
+// output, and optionally GODEBUG=gcpacertrace=1 output.
+//
+// Allocation and scan rates are treated as bytes per nanosecond of wall-clock
+// time, with all procs CPUs available. Stop-the-world phases, sweeping, and
+// idle mark workers aren't modeled, so they always show up as zero.
+func printGCTrace(ex *scenario.Execution, r []simulation.Result, procs int, pacerTrace bool) {
+	c := ex.Cycles
+	var now, gcCPUTime float64
+	var liveLast, liveScanLast uint64
+	for i := range r {
+		// Figure out how long the mutator ran alone, and how long the
+		// mark phase took, from how much was allocated in each.
+		now += math.Max(float64(r[i].TriggerPoint)-float64(liveLast), 0) / c[i].AllocRate
+		start := now
+		u := r[i].ActualGCUtilization
+		var markTime float64
+		if u < 1 && r[i].PeakBytes > r[i].TriggerPoint {
+			markTime = float64(r[i].PeakBytes-r[i].TriggerPoint) / (c[i].AllocRate * (1 - u))
+		} else {
+			markTime = float64(r[i].LiveScanBytes) / (c[i].ScanRate * u)
+		}
+		now += markTime
+		gcCPUTime += u * markTime
+
+		bgU := math.Min(u, r[i].TargetGCUtilization)
+		bgCPU := bgU * markTime * float64(procs)
+		assistCPU := (u - bgU) * markTime * float64(procs)
+
+		if pacerTrace {
+			expScanWork := liveScanLast + c[i].StackBytes + ex.Globals.GlobalsBytes
+			assistRatio := float64(r[i].GoalBytes-r[i].TriggerPoint) / float64(expScanWork)
+			workers := r[i].TargetGCUtilization * float64(procs)
+			dedicated := math.Floor(workers)
+			fmt.Printf("pacer: assist ratio=%s (scan %d MB in %d->%d MB) workers=%d+%s\n",
+				printFloat(assistRatio),
+				liveScanLast>>20,
+				r[i].TriggerPoint>>20,
+				r[i].GoalBytes>>20,
+				int(dedicated),
+				printFloat((workers-dedicated)/float64(procs)),
+			)
+
+			scanWork := uint64(c[i].ScanRate * u * markTime)
+			nonHeapScanWork := c[i].StackBytes + ex.Globals.GlobalsBytes
+			var heapScanWork uint64
+			if scanWork > nonHeapScanWork {
+				heapScanWork = scanWork - nonHeapScanWork
+			}
+			fmt.Printf("pacer: %d%% CPU (%d exp.) for %d+%d+%d B work (%d B exp.) in %d B -> %d B (∆goal %d, cons/mark %s)\n",
+				int(u*100),
+				int(r[i].TargetGCUtilization*100),
+				heapScanWork,
+				c[i].StackBytes,
+				ex.Globals.GlobalsBytes,
+				expScanWork,
+				r[i].TriggerPoint,
+				r[i].PeakBytes,
+				int64(r[i].PeakBytes)-int64(r[i].GoalBytes),
+				printFloat(c[i].AllocRate/c[i].ScanRate),
+			)
+		}
+
+		fmt.Printf("gc %d @%.3fs %d%%: %s+%s+%s ms clock, %s+%s/%s/%s+%s ms cpu, %d->%d->%d MB, %d MB goal, %d MB stacks, %d MB globals, %d P\n",
+			i+1,
+			start/1e9,
+			int(gcCPUTime/now*100),
+			fmtNSAsMS(0),
+			fmtNSAsMS(markTime),
+			fmtNSAsMS(0),
+			fmtNSAsMS(0),
+			fmtNSAsMS(assistCPU),
+			fmtNSAsMS(bgCPU),
+			fmtNSAsMS(0),
+			fmtNSAsMS(0),
+			r[i].TriggerPoint>>20,
+			r[i].PeakBytes>>20,
+			r[i].LiveBytes>>20,
+			r[i].GoalBytes>>20,
+			c[i].StackBytes>>20,
+			ex.Globals.GlobalsBytes>>20,
+			procs,
+		)
+		liveLast = r[i].LiveBytes
+		liveScanLast = r[i].LiveScanBytes
+	}
+}
+
+// fmtNSAsMS formats a duration in nanoseconds as milliseconds, the same way
+// the runtime does: whole milliseconds for durations of at least 10 ms,
+// otherwise two digits of precision with at most three decimal places.
+func fmtNSAsMS(ns float64) string {
+	if ns >= 10e6 {
+		return fmt.Sprintf("%d", uint64(ns/1e6))
+	}
+	x := uint64(ns / 1e3)
+	if x == 0 {
+		return "0"
+	}
+	dec := 3
+	for x >= 100 {
+		x /= 10
+		dec--
+	}
+	return fmt.Sprintf("%.*f", dec, float64(x)/math.Pow10(dec))
+}
+
+// printFloat formats a float64 the same way the runtime's print does,
+// for example +1.234560e+000.
+func printFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	sign := "+"
+	if math.Signbit(v) {
+		sign = "-"
+		v = -v
+	}
+	var exp int
+	if v != 0 {
+		exp = int(math.Floor(math.Log10(v)))
+		v /= math.Pow10(exp)
+		if v >= 10 {
+			v /= 10
+			exp++
+		}
+	}
+	expSign := "+"
+	if exp < 0 {
+		expSign = "-"
+		exp = -exp
+	}
+	return fmt.Sprintf("%s%.6fe%s%03d", sign, v, expSign, exp)
+}
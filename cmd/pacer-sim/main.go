@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/mknyszek/pacer-model/controller"
@@ -14,9 +15,16 @@ import (
 )
 
 var (
-	genJSONFlag    *bool   = flag.Bool("json", false, "generate a JSON file instead of a CSV")
-	ctrlConfigFlag *string = flag.String("controller-config", "", "file containing JSON controller configuration (optional, default parameters used otherwise)")
+	genJSONFlag    *bool   = flag.Bool("json", false, "generate a JSON file instead of a CSV (same as -format=json)")
+	formatFlag     *string = flag.String("format", "csv", "output format: csv, json, or gctrace")
+	pacerTraceFlag *bool   = flag.Bool("gcpacertrace", false, "with -format=gctrace, also emit gcpacertrace-style lines")
+	procsFlag      *int    = flag.Int("procs", 8, "with -format=gctrace, the number of Ps to report CPU times for")
+	ctrlConfigFlag *string = flag.String("controller-config", "", "file containing JSON controller configuration, whose \"type\" field selects the controller (optional, default parameters used otherwise)")
+	simConfigFlag  *string = flag.String("sim-config", "", "file containing JSON simulator configuration (optional, overrides the pacer's default tunables)")
+	cpuLimiterFlag *bool   = flag.Bool("cpu-limiter", false, "model the GC CPU limiter")
+	traceFlag      *bool   = flag.Bool("trace", false, "include pacer internals for each GC cycle in the output")
 	listFlag       *bool   = flag.Bool("l", false, "list available pacers")
+	listCtrlFlag   *bool   = flag.Bool("lc", false, "list available controller types")
 )
 
 func run() error {
@@ -26,10 +34,23 @@ func run() error {
 		fmt.Println(strings.Join(simulation.Simulators(), "\n"))
 		return nil
 	}
+	if *listCtrlFlag {
+		fmt.Println(strings.Join(controller.Controllers(), "\n"))
+		return nil
+	}
 
 	if flag.NArg() != 2 {
 		return fmt.Errorf("expected 2 arguments: pacer type and scenario file")
 	}
+	format := *formatFlag
+	if *genJSONFlag {
+		format = "json"
+	}
+	switch format {
+	case "csv", "json", "gctrace":
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
 
 	// Parse scenario.
 	scnData, err := ioutil.ReadFile(flag.Arg(1))
@@ -48,15 +69,35 @@ func run() error {
 		if err != nil {
 			return err
 		}
-		var ctrlCfg controller.PIConfig
-		if err := json.Unmarshal(ctrlData, &ctrlCfg); err != nil {
+		ctrl, err = controller.New(ctrlData)
+		if err != nil {
 			return fmt.Errorf("unmarshalling controller config: %v", err)
 		}
-		ctrl = controller.NewPI(&ctrlCfg)
+	}
+
+	// Parse simulator configuration.
+	simCfg, err := simulation.DefaultConfig(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *simConfigFlag != "" {
+		simData, err := ioutil.ReadFile(*simConfigFlag)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(simData, &simCfg); err != nil {
+			return fmt.Errorf("unmarshalling simulator config: %v", err)
+		}
+	}
+	if *cpuLimiterFlag {
+		simCfg.CPULimiter = true
+	}
+	if *traceFlag {
+		simCfg.Trace = true
 	}
 
 	// Pick a simulator and inject a controller.
-	s, err := simulation.NewSimulator(flag.Arg(0), scn.Globals, ctrl)
+	s, err := simulation.NewSimulator(flag.Arg(0), &scn, ctrl, &simCfg)
 	if err != nil {
 		return err
 	}
@@ -68,13 +109,16 @@ func run() error {
 	}
 
 	// Write output.
-	if *genJSONFlag {
+	switch format {
+	case "json":
 		results, err := json.Marshal(r)
 		if err != nil {
 			return fmt.Errorf("marshalling results: %v", err)
 		}
 		fmt.Println(string(results))
-	} else {
+	case "gctrace":
+		printGCTrace(&scn, r, *procsFlag, *pacerTraceFlag)
+	default:
 		printCSV(&scn, r)
 	}
 	return nil
@@ -88,10 +132,20 @@ func main() {
 }
 
 func printCSV(ex *scenario.Execution, r []simulation.Result) {
-	fmt.Println("Gamma,Globals Bytes,Allocation Rate,Growth Rate,Scan Rate,Scannable Rate,Stack Bytes,R,Live Bytes,Scannable Live Bytes,Goal,Actual Utilization,Target Utilization,Trigger,Peak")
+	names := traceNames(r, func(r *simulation.Result) simulation.Trace { return r.Trace })
+	ctrlNames := traceNames(r, func(r *simulation.Result) simulation.Trace { return r.Controller })
+
+	fmt.Print("Gamma,Globals Bytes,Allocation Rate,Growth Rate,Scan Rate,Scannable Rate,Stack Bytes,R,Live Bytes,Scannable Live Bytes,Goal,Actual Utilization,Target Utilization,Trigger,Peak,CPU Limited,R Variance")
+	for _, name := range names {
+		fmt.Printf(",%s", name)
+	}
+	for _, name := range ctrlNames {
+		fmt.Printf(",Controller %s", name)
+	}
+	fmt.Println()
 	c := ex.Cycles
 	for i := range r {
-		fmt.Printf("%f,%d,%f,%f,%f,%f,%d,%f,%d,%d,%d,%f,%f,%d,%d\n",
+		fmt.Printf("%f,%d,%f,%f,%f,%f,%d,%f,%d,%d,%d,%f,%f,%d,%d,%d,%f",
 			ex.Globals.Gamma,
 			ex.Globals.GlobalsBytes,
 			c[i].AllocRate,
@@ -107,6 +161,45 @@ func printCSV(ex *scenario.Execution, r []simulation.Result) {
 			r[i].TargetGCUtilization,
 			r[i].TriggerPoint,
 			r[i].PeakBytes,
+			btoi(r[i].CPULimited),
+			r[i].RVariance,
 		)
+		printTrace(r[i].Trace, names)
+		printTrace(r[i].Controller, ctrlNames)
+		fmt.Println()
+	}
+}
+
+// traceNames collects the names of all values in a trace across all
+// cycles, since not every value is recorded every cycle.
+func traceNames(r []simulation.Result, trace func(*simulation.Result) simulation.Trace) []string {
+	seen := make(map[string]bool)
+	for i := range r {
+		for _, name := range trace(&r[i]).Names() {
+			seen[name] = true
+		}
+	}
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printTrace(t simulation.Trace, names []string) {
+	for _, name := range names {
+		if v, ok := t[name]; ok {
+			fmt.Printf(",%f", v)
+		} else {
+			fmt.Print(",")
+		}
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
 	}
+	return 0
 }
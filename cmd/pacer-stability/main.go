@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/mknyszek/pacer-model/analysis"
+	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/scenario"
+	"github.com/mknyszek/pacer-model/simulation"
+)
+
+var (
+	kpMinFlag      = flag.Float64("kp-min", 0.05, "smallest Kp")
+	kpMaxFlag      = flag.Float64("kp-max", 5, "largest Kp")
+	tiMinFlag      = flag.Float64("ti-min", 0.1, "smallest Ti")
+	tiMaxFlag      = flag.Float64("ti-max", 20, "largest Ti")
+	stepsFlag      = flag.Int("steps", 30, "number of grid points along each axis, which are spaced logarithmically")
+	ctrlConfigFlag = flag.String("controller-config", "", "file containing the JSON PI controller configuration for everything but Kp and Ti (default the pacer's default)")
+	simConfigFlag  = flag.String("sim-config", "", "file containing JSON simulator configuration (optional, overrides the pacer's default tunables)")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if flag.NArg() != 2 {
+		return fmt.Errorf("expected 2 arguments: pacer type and scenario file")
+	}
+	name := flag.Arg(0)
+	if *stepsFlag < 2 {
+		return fmt.Errorf("need at least 2 steps")
+	}
+
+	baseCfg, err := simulation.DefaultControllerConfig(name)
+	if err != nil {
+		return err
+	}
+	if *ctrlConfigFlag != "" {
+		data, err := ioutil.ReadFile(*ctrlConfigFlag)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &baseCfg); err != nil {
+			return fmt.Errorf("unmarshalling controller config: %v", err)
+		}
+	}
+	simCfg, err := simulation.DefaultConfig(name)
+	if err != nil {
+		return err
+	}
+	if *simConfigFlag != "" {
+		data, err := ioutil.ReadFile(*simConfigFlag)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &simCfg); err != nil {
+			return fmt.Errorf("unmarshalling simulator config: %v", err)
+		}
+	}
+	scnData, err := ioutil.ReadFile(flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	var scn scenario.Execution
+	if err := json.Unmarshal(scnData, &scn); err != nil {
+		return fmt.Errorf("unmarshalling scenario data: %v", err)
+	}
+
+	fmt.Println("Kp,Ti,Stability")
+	kps := logSpace(*kpMinFlag, *kpMaxFlag, *stepsFlag)
+	tis := logSpace(*tiMinFlag, *tiMaxFlag, *stepsFlag)
+	for _, kp := range kps {
+		for _, ti := range tis {
+			cfg := baseCfg
+			cfg.Kp = kp
+			cfg.Ti = ti
+			sc := simCfg
+			s, err := simulation.NewSimulator(name, &scn, controller.NewPI(&cfg), &sc)
+			if err != nil {
+				return err
+			}
+			var r []simulation.Result
+			for i := range scn.Cycles {
+				r = append(r, s.Step(&scn.Cycles[i]))
+			}
+			fmt.Printf("%f,%f,%d\n", kp, ti, analysis.Classify(r))
+		}
+	}
+	return nil
+}
+
+// logSpace returns n values from min to max, spaced logarithmically.
+func logSpace(min, max float64, n int) []float64 {
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = min * math.Pow(max/min, float64(i)/float64(n-1))
+	}
+	return v
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/scenario"
+	"github.com/mknyszek/pacer-model/simulation"
+)
+
+var (
+	outputFlag    = flag.String("o", "", "file to write the best controller configuration to (default stdout)")
+	initFlag      = flag.String("init", "", "file containing the JSON PI controller configuration to start from (default the pacer's default)")
+	itersFlag     = flag.Int("iters", 200, "maximum number of search iterations")
+	overshootFlag = flag.Float64("overshoot-weight", 1, "weight of the mean heap overshoot past the goal, as a fraction of the goal")
+	utilFlag      = flag.Float64("util-weight", 1, "weight of the mean absolute error between actual and target GC utilization")
+	rVarianceFlag = flag.Float64("r-variance-weight", 1, "weight of the variance of R")
+	simConfigFlag = flag.String("sim-config", "", "file containing JSON simulator configuration (optional, overrides the pacer's default tunables)")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if flag.NArg() < 2 {
+		return fmt.Errorf("expected at least 2 arguments: pacer type and scenario files")
+	}
+	name := flag.Arg(0)
+
+	// Parse the starting controller configuration.
+	initCfg, err := simulation.DefaultControllerConfig(name)
+	if err != nil {
+		return err
+	}
+	if *initFlag != "" {
+		data, err := ioutil.ReadFile(*initFlag)
+		if err != nil {
+			return err
+		}
+		// Only PI controllers can be tuned, so reject other types rather
+		// than drop everything but their PI parameters.
+		var typ struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &typ); err != nil {
+			return fmt.Errorf("unmarshalling controller config: %v", err)
+		}
+		if typ.Type != "" && typ.Type != "pi" {
+			return fmt.Errorf("can only tune PI controllers, not %q", typ.Type)
+		}
+		if err := json.Unmarshal(data, &initCfg); err != nil {
+			return fmt.Errorf("unmarshalling controller config: %v", err)
+		}
+	}
+
+	// Parse simulator configuration.
+	simCfg, err := simulation.DefaultConfig(name)
+	if err != nil {
+		return err
+	}
+	if *simConfigFlag != "" {
+		data, err := ioutil.ReadFile(*simConfigFlag)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &simCfg); err != nil {
+			return fmt.Errorf("unmarshalling simulator config: %v", err)
+		}
+	}
+
+	// Parse scenarios.
+	var scns []scenario.Execution
+	var names []string
+	for _, path := range flag.Args()[1:] {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var scn scenario.Execution
+		if err := json.Unmarshal(data, &scn); err != nil {
+			return fmt.Errorf("unmarshalling scenario %q: %v", path, err)
+		}
+		scns = append(scns, scn)
+		names = append(names, strings.TrimSuffix(filepath.Base(path), ".json"))
+	}
+
+	t := &tuner{
+		name:   name,
+		simCfg: simCfg,
+		scns:   scns,
+		weights: costWeights{
+			overshoot: *overshootFlag,
+			util:      *utilFlag,
+			rVariance: *rVarianceFlag,
+		},
+	}
+	best, err := t.tune(initCfg, *itersFlag)
+	if err != nil {
+		return err
+	}
+
+	// Write output.
+	if err := writeConfig(best, *outputFlag); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Scenario\tOvershoot\tUtilization Error\tR Variance\tCost\tDefault Cost")
+	var total, totalInit float64
+	for i := range scns {
+		s, err := t.score(&best, &scns[i])
+		if err != nil {
+			return err
+		}
+		s0, err := t.score(&initCfg, &scns[i])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%f\t%f\t%f\t%f\t%f\n", names[i], s.overshoot, s.util, s.rVariance, s.cost, s0.cost)
+		total += s.cost
+		totalInit += s0.cost
+	}
+	fmt.Fprintf(w, "total\t\t\t\t%f\t%f\n", total, totalInit)
+	return w.Flush()
+}
+
+func writeConfig(cfg controller.PIConfig, path string) error {
+	out := os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	data, err := json.MarshalIndent(struct {
+		Type string `json:"type"`
+		controller.PIConfig
+	}{"pi", cfg}, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+type costWeights struct {
+	overshoot, util, rVariance float64
+}
+
+type score struct {
+	overshoot, util, rVariance float64
+	cost                       float64
+}
+
+type tuner struct {
+	name    string
+	simCfg  simulation.SimulatorConfig
+	scns    []scenario.Execution
+	weights costWeights
+}
+
+// tune searches for the PI controller configuration with the lowest total
+// cost over all the scenarios, starting from init.
+//
+// Kp, Ti, and Tt are searched in log space, which keeps them positive and
+// lets the search cover several orders of magnitude. Those that are zero in
+// init stay zero, so that the structure of the controller doesn't change:
+// a zero Ti or Tt disables the integral term. The period and output bounds
+// are kept as they are.
+func (t *tuner) tune(init controller.PIConfig, iters int) (controller.PIConfig, error) {
+	params := []*float64{&init.Kp, &init.Ti, &init.Tt}
+	var free []int
+	var x0 []float64
+	for i, p := range params {
+		if *p > 0 {
+			free = append(free, i)
+			x0 = append(x0, math.Log(*p))
+		}
+	}
+	if len(free) == 0 {
+		return controller.PIConfig{}, fmt.Errorf("no nonzero gains to tune")
+	}
+	toConfig := func(x []float64) controller.PIConfig {
+		cfg := init
+		p := []*float64{&cfg.Kp, &cfg.Ti, &cfg.Tt}
+		for j, i := range free {
+			*p[i] = math.Exp(x[j])
+		}
+		return cfg
+	}
+	var err error
+	cost := func(x []float64) float64 {
+		cfg := toConfig(x)
+		var total float64
+		for i := range t.scns {
+			s, e := t.score(&cfg, &t.scns[i])
+			if e != nil {
+				err = e
+				return math.Inf(1)
+			}
+			total += s.cost
+		}
+		return total
+	}
+	x := nelderMead(cost, x0, 0.5, iters)
+	if err != nil {
+		return controller.PIConfig{}, err
+	}
+	return toConfig(x), nil
+}
+
+// score runs a scenario with the given controller configuration
+// and scores the result.
+func (t *tuner) score(cfg *controller.PIConfig, scn *scenario.Execution) (score, error) {
+	simCfg := t.simCfg
+	sim, err := simulation.NewSimulator(t.name, scn, controller.NewPI(cfg), &simCfg)
+	if err != nil {
+		return score{}, err
+	}
+	var s score
+	var rs []float64
+	for i := range scn.Cycles {
+		r := sim.Step(&scn.Cycles[i])
+		if r.PeakBytes > r.GoalBytes && r.GoalBytes > 0 {
+			s.overshoot += float64(r.PeakBytes-r.GoalBytes) / float64(r.GoalBytes)
+		}
+		s.util += math.Abs(r.ActualGCUtilization - r.TargetGCUtilization)
+		if i > 0 {
+			// R isn't meaningful until after the first cycle.
+			rs = append(rs, r.R)
+		}
+	}
+	n := float64(len(scn.Cycles))
+	s.overshoot /= n
+	s.util /= n
+	if len(rs) > 0 {
+		var mean float64
+		for _, r := range rs {
+			mean += r
+		}
+		mean /= float64(len(rs))
+		for _, r := range rs {
+			s.rVariance += (r - mean) * (r - mean)
+		}
+		s.rVariance /= float64(len(rs))
+	}
+	s.cost = t.weights.overshoot*s.overshoot + t.weights.util*s.util + t.weights.rVariance*s.rVariance
+	if math.IsNaN(s.cost) {
+		s.cost = math.Inf(1)
+	}
+	return s, nil
+}
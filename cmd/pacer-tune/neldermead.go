@@ -0,0 +1,87 @@
+package main
+
+import "sort"
+
+// nelderMead minimizes f using the Nelder-Mead simplex method, starting
+// from a simplex around x0 with the given step size along each axis, and
+// returns the best point found after at most iters iterations.
+func nelderMead(f func([]float64) float64, x0 []float64, step float64, iters int) []float64 {
+	const (
+		alpha = 1.0 // Reflection.
+		gamma = 2.0 // Expansion.
+		rho   = 0.5 // Contraction.
+		sigma = 0.5 // Shrinkage.
+		tol   = 1e-9
+	)
+	n := len(x0)
+	type vertex struct {
+		x []float64
+		f float64
+	}
+	simplex := make([]vertex, n+1)
+	for i := range simplex {
+		x := append([]float64(nil), x0...)
+		if i > 0 {
+			x[i-1] += step
+		}
+		simplex[i] = vertex{x, f(x)}
+	}
+	// along returns c + t*(x - c).
+	along := func(c, x []float64, t float64) []float64 {
+		y := make([]float64, n)
+		for i := range y {
+			y[i] = c[i] + t*(x[i]-c[i])
+		}
+		return y
+	}
+	for iter := 0; iter < iters; iter++ {
+		sort.Slice(simplex, func(i, j int) bool {
+			return simplex[i].f < simplex[j].f
+		})
+		best, worst := simplex[0], simplex[n]
+		if worst.f-best.f < tol {
+			break
+		}
+
+		// Centroid of all but the worst vertex.
+		c := make([]float64, n)
+		for _, v := range simplex[:n] {
+			for i := range c {
+				c[i] += v.x[i] / float64(n)
+			}
+		}
+
+		xr := along(c, worst.x, -alpha)
+		fr := f(xr)
+		switch {
+		case fr < best.f:
+			xe := along(c, worst.x, -gamma)
+			if fe := f(xe); fe < fr {
+				simplex[n] = vertex{xe, fe}
+			} else {
+				simplex[n] = vertex{xr, fr}
+			}
+		case fr < simplex[n-1].f:
+			simplex[n] = vertex{xr, fr}
+		default:
+			// Contract toward the better of the worst and
+			// reflected points.
+			xc := along(c, worst.x, rho)
+			if fr < worst.f {
+				xc = along(c, xr, rho)
+			}
+			if fc := f(xc); fc < worst.f && fc < fr {
+				simplex[n] = vertex{xc, fc}
+				continue
+			}
+			for i := 1; i <= n; i++ {
+				x := along(best.x, simplex[i].x, sigma)
+				simplex[i] = vertex{x, f(x)}
+			}
+		}
+	}
+	sort.Slice(simplex, func(i, j int) bool {
+		return simplex[i].f < simplex[j].f
+	})
+	return simplex[0].x
+}
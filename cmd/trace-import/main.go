@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mknyszek/pacer-model/importer"
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+var (
+	outputFlag      = flag.String("o", "", "file to write the scenario to (default stdout)")
+	utilizationFlag = flag.String("u", "", "file to write the measured GC CPU utilization of each cycle to as CSV (execution traces only)")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if flag.NArg() > 1 {
+		return fmt.Errorf("expected at most 1 argument: a file containing GODEBUG=gctrace=1 output or an execution trace (default stdin)")
+	}
+	var in io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	br := bufio.NewReader(in)
+	hdr, _ := br.Peek(16)
+	if !importer.IsExecTrace(hdr) {
+		if *utilizationFlag != "" {
+			return fmt.Errorf("-u is only supported for execution traces")
+		}
+		e, err := importer.GCTrace(br)
+		if err != nil {
+			return fmt.Errorf("importing trace: %v", err)
+		}
+		return writeScenario(e, *outputFlag)
+	}
+	e, u, err := importer.ExecTrace(br)
+	if err != nil {
+		return fmt.Errorf("importing execution trace: %v", err)
+	}
+	if *utilizationFlag != "" {
+		if err := writeUtilization(u, *utilizationFlag); err != nil {
+			return err
+		}
+	}
+	return writeScenario(e, *outputFlag)
+}
+
+func writeUtilization(u []float64, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "Cycle,Measured Utilization")
+	for i := range u {
+		fmt.Fprintf(w, "%d,%f\n", i, u[i])
+	}
+	return w.Flush()
+}
+
+func writeScenario(e scenario.Execution, path string) error {
+	out := os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "    ")
+	return enc.Encode(&e)
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+)
+
+// Adaptive is a self-tuning PI controller.
+//
+// It identifies a first-order model of the loop's error e = setpoint - input,
+//
+//	e[k] = A*e[k-1] - Gain*(input[k] - input[k-1])
+//
+// online with recursive least squares, and places both closed-loop poles of
+// the PI controller on that model at Pole every cycle. A pure integrator
+// with a constant setpoint has A = Gain = 1.
+type Adaptive struct {
+	AdaptiveConfig
+	pi PI
+
+	// theta is the identified [A, Gain], and p is its covariance.
+	theta [2]float64
+	p     [2][2]float64
+
+	lastInput, lastErr float64
+	started            bool
+}
+
+type AdaptiveConfig struct {
+	// PIConfig is the initial configuration of the PI controller. Its gains
+	// are used until the plant gain has been identified.
+	PIConfig
+
+	// Forgetting is the RLS forgetting factor in (0, 1]. Smaller values
+	// track changes in the plant faster, but are noisier.
+	Forgetting float64 `json:"forgetting"`
+
+	// Pole is the desired closed-loop pole, in [0, 1). Smaller values
+	// respond faster.
+	Pole float64 `json:"pole"`
+
+	// InitialCovariance is the initial covariance of the parameter
+	// estimates, that is, how little the nominal plant is trusted.
+	InitialCovariance float64 `json:"initial_covariance"`
+}
+
+const (
+	// adaptiveMinGain is the smallest identified plant gain that the
+	// controller will retune for.
+	adaptiveMinGain = 0.05
+
+	// adaptiveMaxCovariance bounds the trace of the RLS covariance,
+	// which otherwise grows without bound without excitation.
+	adaptiveMaxCovariance = 1e4
+)
+
+// validate checks that the forgetting factor and pole are in range.
+func (cfg *AdaptiveConfig) validate() error {
+	if cfg.Forgetting <= 0 || cfg.Forgetting > 1 {
+		return fmt.Errorf("forgetting factor must be in (0, 1], got %v", cfg.Forgetting)
+	}
+	if cfg.Pole < 0 || cfg.Pole >= 1 {
+		return fmt.Errorf("pole must be in [0, 1), got %v", cfg.Pole)
+	}
+	return nil
+}
+
+func NewAdaptive(cfg *AdaptiveConfig) *Adaptive {
+	c := &Adaptive{AdaptiveConfig: *cfg}
+	c.pi.PIConfig = cfg.PIConfig
+	c.theta = [2]float64{1, 1}
+	c.p = [2][2]float64{{cfg.InitialCovariance, 0}, {0, cfg.InitialCovariance}}
+	return c
+}
+
+// Plant returns the currently identified plant parameters.
+func (c *Adaptive) Plant() (a, gain float64) {
+	return c.theta[0], c.theta[1]
+}
+
+// Gains returns the PI controller's current gains.
+func (c *Adaptive) Gains() (kp, ti float64) {
+	return c.pi.Kp, c.pi.Ti
+}
+
+func (c *Adaptive) Next(input, setpoint float64) float64 {
+	err := setpoint - input
+	if c.started {
+		c.identify([2]float64{c.lastErr, -(input - c.lastInput)}, err)
+		c.retune()
+	}
+	c.lastInput, c.lastErr = input, err
+	c.started = true
+	return c.pi.Next(input, setpoint)
+}
+
+// identify runs one step of recursive least squares with regressor phi
+// and measurement y.
+func (c *Adaptive) identify(phi [2]float64, y float64) {
+	var pphi [2]float64
+	for i := range pphi {
+		pphi[i] = c.p[i][0]*phi[0] + c.p[i][1]*phi[1]
+	}
+	denom := c.Forgetting + phi[0]*pphi[0] + phi[1]*pphi[1]
+	if denom <= 0 {
+		return
+	}
+	resid := y - (c.theta[0]*phi[0] + c.theta[1]*phi[1])
+	for i := range c.theta {
+		c.theta[i] += pphi[i] / denom * resid
+	}
+	for i := range c.p {
+		for j := range c.p[i] {
+			c.p[i][j] = (c.p[i][j] - pphi[i]*pphi[j]/denom) / c.Forgetting
+		}
+	}
+	if tr := c.p[0][0] + c.p[1][1]; tr > adaptiveMaxCovariance {
+		s := adaptiveMaxCovariance / tr
+		for i := range c.p {
+			for j := range c.p[i] {
+				c.p[i][j] *= s
+			}
+		}
+	}
+}
+
+// retune places the closed-loop poles of the PI controller on the
+// identified plant. With integral gain Ki = Kp * Period / Ti, the
+// closed-loop characteristic polynomial is
+//
+//	z^2 + (Gain*Kp - 1 - A)z + (A + Gain*(Ki - Kp))
+//
+// so a double pole at Pole gives
+//
+//	Kp = (1 + A - 2*Pole) / Gain
+//	Ki = (Pole^2 - A) / Gain + Kp
+func (c *Adaptive) retune() {
+	a, gain := c.theta[0], c.theta[1]
+	if gain < adaptiveMinGain || math.IsNaN(a) {
+		return
+	}
+	kp := (1 + a - 2*c.Pole) / gain
+	ki := (c.Pole*c.Pole-a)/gain + kp
+	if kp <= 0 || ki <= 0 {
+		return
+	}
+	c.pi.Kp = kp
+	c.pi.Ti = kp * c.Period / ki
+}
+
+func (c *Adaptive) Track(input, setpoint, output float64) {
+	c.pi.Track(input, setpoint, output)
+}
+
+func (c *Adaptive) Introspect() map[string]float64 {
+	m := c.pi.Introspect()
+	m["plant_a"], m["plant_gain"] = c.Plant()
+	m["k_p"], m["t_i"] = c.Gains()
+	return m
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestAdaptiveConfig(t *testing.T) {
+	for _, tc := range []struct {
+		config string
+		ok     bool
+	}{
+		{`{"type": "adaptive", "forgetting": 0.9, "pole": 0.5}`, true},
+		{`{"type": "adaptive", "forgetting": 1, "pole": 0}`, true},
+		{`{"type": "adaptive", "pole": 0.5}`, false},
+		{`{"type": "adaptive", "forgetting": -0.5, "pole": 0.5}`, false},
+		{`{"type": "adaptive", "forgetting": 1.1, "pole": 0.5}`, false},
+		{`{"type": "adaptive", "forgetting": 0.9, "pole": 1}`, false},
+		{`{"type": "adaptive", "forgetting": 0.9, "pole": -0.1}`, false},
+	} {
+		_, err := New(json.RawMessage(tc.config))
+		if tc.ok && err != nil {
+			t.Errorf("New(%s): %v", tc.config, err)
+		} else if !tc.ok && err == nil {
+			t.Errorf("New(%s) succeeded, want an error", tc.config)
+		}
+	}
+}
+
+func TestAdaptiveShippedConfig(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("..", "data", "config", "controller-adaptive.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(data); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,3 +3,18 @@ package controller
 type Controller interface {
 	Next(input, setpoint float64) float64
 }
+
+// Bumpless is a Controller whose state can be initialized so that its next
+// output for input and setpoint is output, so that it can take over from
+// another controller without a bump in the output.
+type Bumpless interface {
+	Controller
+	Track(input, setpoint, output float64)
+}
+
+// Introspector is a Controller that can report named internal signals from
+// its last step, such as the terms that made up its output.
+type Introspector interface {
+	Controller
+	Introspect() map[string]float64
+}
@@ -0,0 +1,135 @@
+package controller
+
+import "math"
+
+// MPC is a model-predictive controller for the go117 pacer's R value.
+//
+// Each cycle it predicts the next Horizon cycles with a model of the go117
+// trigger and peak heap equations, and picks the sequence of changes to R
+// that minimizes the predicted error in the peak heap relative to the heap
+// goal, the predicted GC CPU utilization error, and the size of the changes.
+// The true alloc/scan ratio, that is, the setpoint, is assumed to stay the
+// same over the horizon. Only the first change is applied.
+type MPC struct {
+	MPCConfig
+}
+
+type MPCConfig struct {
+	Horizon int `json:"horizon"`
+
+	// Weights of the squared error terms in the cost function.
+	HeapWeight        float64 `json:"heap_weight"`
+	UtilizationWeight float64 `json:"utilization_weight"`
+	MoveWeight        float64 `json:"move_weight"`
+
+	// Min and Max bound each change.
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+const (
+	// mpcSteps is the number of candidate changes considered in each
+	// search, evenly spaced across the search window.
+	mpcSteps = 64
+
+	// mpcSweeps is the number of coordinate descent sweeps over the
+	// horizon. The first sweep searches all of [Min, Max], and each
+	// one after that searches a window mpcShrink times narrower
+	// around the best change so far.
+	mpcSweeps = 6
+	mpcShrink = 8
+)
+
+func NewMPC(cfg *MPCConfig) *MPC {
+	return &MPC{MPCConfig: *cfg}
+}
+
+// Next chooses the output without any context about the pacer, by assuming
+// a heap goal of twice the live heap, which is all scannable, and a target
+// utilization of 25%.
+func (c *MPC) Next(input, setpoint float64) float64 {
+	return c.Observe(&Observation{
+		Input:             input,
+		Setpoint:          setpoint,
+		Gamma:             2,
+		HeapGoal:          2,
+		ExpScanWork:       1,
+		ScanWork:          1,
+		TargetUtilization: 0.25,
+		InputMin:          math.Inf(-1),
+		InputMax:          math.Inf(1),
+	})
+}
+
+func (c *MPC) Observe(obs *Observation) float64 {
+	if c.Horizon <= 0 {
+		return 0
+	}
+	moves := make([]float64, c.Horizon)
+	best := c.cost(obs, moves)
+	width := c.Max - c.Min
+	for sweep := 0; sweep < mpcSweeps; sweep++ {
+		step := width / mpcSteps
+		for i := range moves {
+			lo := c.Min
+			if sweep > 0 {
+				lo = math.Max(c.Min, moves[i]-width/2)
+			}
+			orig := moves[i]
+			for j := 0; j <= mpcSteps; j++ {
+				m := lo + float64(j)*step
+				if m > c.Max {
+					break
+				}
+				moves[i] = m
+				if cost := c.cost(obs, moves); cost < best {
+					best = cost
+					orig = m
+				}
+			}
+			moves[i] = orig
+		}
+		width /= mpcShrink
+	}
+	return moves[0]
+}
+
+// cost predicts the cost of applying moves to the input over the horizon.
+func (c *MPC) cost(obs *Observation, moves []float64) float64 {
+	rTrue := obs.Setpoint
+	u := obs.TargetUtilization
+	r := obs.Input
+	var cost float64
+	for _, m := range moves {
+		r += m
+		if r < obs.InputMin {
+			r = obs.InputMin
+		} else if r > obs.InputMax {
+			r = obs.InputMax
+		}
+		heapErr, uErr := predict(obs, r, rTrue, u)
+		cost += c.HeapWeight*heapErr*heapErr + c.UtilizationWeight*uErr*uErr + c.MoveWeight*m*m
+	}
+	return cost
+}
+
+// predict predicts the go117 pacer's peak heap error, relative to the heap
+// goal, and GC CPU utilization error for a cycle paced with ratio r, if the
+// cycle actually needed ratio rTrue at utilization u.
+func predict(obs *Observation, r, rTrue, u float64) (heapErr, uErr float64) {
+	// The trigger is r * expScanWork below the heap goal, and assists
+	// keep the ratio of allocation to scan work at or below r.
+	//
+	// peak = goal - r * expScanWork + min(rTrue, r) * scanWork
+	actual := rTrue
+	actualU := u
+	if r < rTrue {
+		actual = r
+		// See go117 for the derivation, noting that
+		// allocRate / scanRate = rTrue * u / (1 - u).
+		x := rTrue * u / ((1 - u) * r)
+		actualU = x / (1 + x)
+	}
+	heapErr = (actual*obs.ScanWork - r*obs.ExpScanWork) / obs.HeapGoal
+	return heapErr, actualU - u
+}
@@ -0,0 +1,43 @@
+package controller
+
+// Observation is what a pacer observed in a GC cycle, for controllers that
+// need more context than the input and setpoint to choose their output.
+type Observation struct {
+	// Input and Setpoint are as for Controller.Next.
+	Input    float64
+	Setpoint float64
+
+	// Gamma is the heap growth target, that is, GOGC/100 + 1.
+	Gamma float64
+
+	// HeapGoal is the cycle's heap goal, LiveHeap is the live heap at the
+	// start of the cycle, and ExpScanWork and ScanWork are the scan work
+	// the pacer expected and the actual scan work, all in bytes.
+	HeapGoal    float64
+	LiveHeap    float64
+	ExpScanWork float64
+	ScanWork    float64
+
+	// HeapTargetActive indicates that the heap goal was set by the
+	// heap target rather than Gamma.
+	HeapTargetActive bool
+
+	// TargetUtilization is the GC CPU utilization the pacer targets.
+	TargetUtilization float64
+
+	// MeasurementDelay is the number of cycles by which the observation
+	// lags behind the pacer. Input is as of then, too.
+	MeasurementDelay int
+
+	// InputMin and InputMax are the bounds the pacer clamps the input to
+	// after applying the controller's output.
+	InputMin float64
+	InputMax float64
+}
+
+// Observer is a Controller that can make use of an Observation.
+// Pacers that can provide one call Observe instead of Next.
+type Observer interface {
+	Controller
+	Observe(obs *Observation) float64
+}
@@ -3,6 +3,9 @@ package controller
 type PI struct {
 	PIConfig
 	integral float64
+
+	// last are the internal signals from the last step.
+	last piSignals
 }
 
 type PIConfig struct {
@@ -30,14 +33,53 @@ func (c *PI) output(input, setpoint float64) (rawOutput, output float64) {
 	return rawOutput, output
 }
 
-func (c *PI) update(input, setpoint, rawOutput, output float64) {
+func (c *PI) update(input, setpoint, rawOutput, output float64) (windup float64) {
 	if c.Ti != 0 && c.Tt != 0 {
-		c.integral += (c.Kp*c.Period/c.Ti)*(setpoint-input) + (c.Period/c.Tt)*(output-rawOutput)
+		windup = (c.Period / c.Tt) * (output - rawOutput)
+		c.integral += (c.Kp*c.Period/c.Ti)*(setpoint-input) + windup
 	}
+	return windup
 }
 
 func (c *PI) Next(input, setpoint float64) float64 {
+	integral := c.integral
 	rawOutput, output := c.output(input, setpoint)
-	c.update(input, setpoint, rawOutput, output)
+	windup := c.update(input, setpoint, rawOutput, output)
+	c.last = piSignals{
+		proportional: rawOutput - integral,
+		integral:     integral,
+		rawOutput:    rawOutput,
+		output:       output,
+		windup:       windup,
+	}
 	return output
 }
+
+func (c *PI) Track(input, setpoint, output float64) {
+	c.integral = output - c.Kp*(setpoint-input)
+}
+
+func (c *PI) Introspect() map[string]float64 {
+	return c.last.introspect()
+}
+
+// piSignals are the internal signals of a PI controller.
+type piSignals struct {
+	proportional float64
+	integral     float64
+	rawOutput    float64
+	output       float64
+
+	// windup is the anti-windup correction to the integral.
+	windup float64
+}
+
+func (s *piSignals) introspect() map[string]float64 {
+	return map[string]float64{
+		"proportional":      s.proportional,
+		"integral":          s.integral,
+		"raw_output":        s.rawOutput,
+		"output":            s.output,
+		"windup_correction": s.windup,
+	}
+}
@@ -0,0 +1,107 @@
+package controller
+
+type PID struct {
+	PIDConfig
+
+	// b and c are the setpoint weights.
+	b, c float64
+
+	integral   float64
+	derivative float64
+	lastErr    float64
+	started    bool
+
+	// last are the internal signals from the last step.
+	last piSignals
+}
+
+type PIDConfig struct {
+	Kp float64 `json:"k_p"`
+	Ti float64 `json:"t_i"`
+	Td float64 `json:"t_d"`
+	Tt float64 `json:"t_t"`
+
+	// Tf is the time constant of the first-order low-pass filter
+	// on the derivative term. Zero disables filtering.
+	Tf float64 `json:"t_f"`
+
+	// B and C are the setpoint weights for the proportional and
+	// derivative terms respectively. If nil, they default to 1. With
+	// B = C = 1 and Td = 0, a PID behaves exactly like a PI with the
+	// same parameters.
+	B *float64 `json:"b,omitempty"`
+	C *float64 `json:"c,omitempty"`
+
+	Period float64 `json:"period"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+func NewPID(cfg *PIDConfig) *PID {
+	c := &PID{PIDConfig: *cfg, b: 1, c: 1}
+	if cfg.B != nil {
+		c.b = *cfg.B
+	}
+	if cfg.C != nil {
+		c.c = *cfg.C
+	}
+	return c
+}
+
+func (c *PID) output(input, setpoint float64) (rawOutput, output, derivative float64) {
+	prop := c.Kp * (c.b*setpoint - input)
+
+	// Backward difference of the weighted error, low-pass filtered.
+	// There's no derivative kick on the first step.
+	derr := c.c*setpoint - input
+	if c.started && c.Td != 0 {
+		derivative = (c.Tf*c.derivative + c.Kp*c.Td*(derr-c.lastErr)) / (c.Tf + c.Period)
+	}
+
+	rawOutput = prop + c.integral + derivative
+	output = rawOutput
+	if output < c.Min {
+		output = c.Min
+	} else if output > c.Max {
+		output = c.Max
+	}
+	return rawOutput, output, derivative
+}
+
+func (c *PID) update(input, setpoint, derivative, rawOutput, output float64) (windup float64) {
+	if c.Ti != 0 && c.Tt != 0 {
+		windup = (c.Period / c.Tt) * (output - rawOutput)
+		c.integral += (c.Kp*c.Period/c.Ti)*(setpoint-input) + windup
+	}
+	c.derivative = derivative
+	c.lastErr = c.c*setpoint - input
+	c.started = true
+	return windup
+}
+
+func (c *PID) Next(input, setpoint float64) float64 {
+	integral := c.integral
+	rawOutput, output, derivative := c.output(input, setpoint)
+	windup := c.update(input, setpoint, derivative, rawOutput, output)
+	c.last = piSignals{
+		proportional: rawOutput - integral - derivative,
+		integral:     integral,
+		rawOutput:    rawOutput,
+		output:       output,
+		windup:       windup,
+	}
+	return output
+}
+
+func (c *PID) Track(input, setpoint, output float64) {
+	c.integral = output - c.Kp*(c.b*setpoint-input)
+	c.derivative = 0
+	c.lastErr = c.c*setpoint - input
+	c.started = true
+}
+
+func (c *PID) Introspect() map[string]float64 {
+	m := c.last.introspect()
+	m["derivative"] = c.derivative
+	return m
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// stepResponse runs c on a unit step in the setpoint for n steps, treating
+// the controller's output as the change in its input, like go117 does.
+func stepResponse(c Controller, n int) []float64 {
+	var input float64
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = c.Next(input, 1)
+		input += out[i]
+	}
+	return out
+}
+
+func TestPIDDefaultWeights(t *testing.T) {
+	pi := PIConfig{Kp: 0.9, Ti: 1.6, Tt: 1000, Period: 1, Min: -2, Max: 2}
+	want := stepResponse(NewPI(&pi), 20)
+
+	direct := NewPID(&PIDConfig{Kp: pi.Kp, Ti: pi.Ti, Tt: pi.Tt, Period: pi.Period, Min: pi.Min, Max: pi.Max})
+	fromJSON, err := New(json.RawMessage(`{"type": "pid", "k_p": 0.9, "t_i": 1.6, "t_t": 1000, "period": 1, "min": -2, "max": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]Controller{"NewPID": direct, "New": fromJSON} {
+		got := stepResponse(c, len(want))
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: output %d = %f, want %f like a PI", name, i, got[i], want[i])
+				break
+			}
+		}
+	}
+
+	zero, err := New(json.RawMessage(`{"type": "pid", "k_p": 0.9, "t_i": 1.6, "t_t": 1000, "period": 1, "min": -2, "max": 2, "b": 0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := zero.Next(0, 1); got == want[0] {
+		t.Errorf("with b = 0, first output = %f, want a smaller proportional kick", got)
+	}
+}
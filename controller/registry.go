@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+type factory func(json.RawMessage) (Controller, error)
+
+var controllers = map[string]factory{
+	"adaptive": func(data json.RawMessage) (Controller, error) {
+		var cfg AdaptiveConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		return NewAdaptive(&cfg), nil
+	},
+	"pi": func(data json.RawMessage) (Controller, error) {
+		var cfg PIConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		return NewPI(&cfg), nil
+	},
+	"mpc": func(data json.RawMessage) (Controller, error) {
+		var cfg MPCConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		return NewMPC(&cfg), nil
+	},
+	"pid": func(data json.RawMessage) (Controller, error) {
+		var cfg PIDConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		return NewPID(&cfg), nil
+	},
+	"scheduled": func(data json.RawMessage) (Controller, error) {
+		var cfg ScheduledConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		return NewScheduled(&cfg), nil
+	},
+}
+
+func init() {
+	// Wrappers refer to New, so they're added here to
+	// avoid an initialization cycle.
+	controllers["slew-limit"] = func(data json.RawMessage) (Controller, error) {
+		var cfg SlewLimitConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		ctrl, err := New(cfg.Controller)
+		if err != nil {
+			return nil, err
+		}
+		return NewSlewLimit(ctrl, cfg.Rate), nil
+	}
+	controllers["smith-predictor"] = func(data json.RawMessage) (Controller, error) {
+		var cfg SmithPredictorConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		ctrl, err := New(cfg.Controller)
+		if err != nil {
+			return nil, err
+		}
+		return NewSmithPredictor(ctrl, &cfg), nil
+	}
+}
+
+func Controllers() []string {
+	var s []string
+	for name := range controllers {
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return s
+}
+
+// New creates a controller from its JSON configuration. The "type" field
+// of the configuration selects the controller, and the rest of it is the
+// controller's own configuration. A configuration without a type describes
+// a PI controller.
+func New(data json.RawMessage) (Controller, error) {
+	var typ struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &typ); err != nil {
+		return nil, err
+	}
+	if typ.Type == "" {
+		typ.Type = "pi"
+	}
+	f, ok := controllers[typ.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown controller type %q", typ.Type)
+	}
+	return f(data)
+}
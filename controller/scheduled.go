@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+)
+
+// Scheduled is a PI controller whose gains are scheduled on the pacer's
+// operating point.
+//
+// Its gains are interpolated between the points of a schedule by inverse
+// distance weighting in log space, over the operating point variables the
+// points specify. When they change, the controller's state is adjusted so
+// that its output doesn't jump.
+//
+// Scheduling requires an Observation. Next uses the current gains, which
+// are initially those of the first point.
+type Scheduled struct {
+	pi       PI
+	schedule []SchedulePoint
+}
+
+type ScheduledConfig struct {
+	// PIConfig configures everything about the PI controller
+	// except its gains.
+	PIConfig
+
+	Schedule []SchedulePoint `json:"schedule"`
+}
+
+// SchedulePoint is a point in a gain schedule.
+type SchedulePoint struct {
+	// Gamma and LiveHeap are operating point variables. Zero values
+	// don't take part in scheduling. All points that specify any of them
+	// must specify the same ones, so that distances between points are
+	// comparable. A point without any applies only where no other
+	// point does.
+	Gamma    float64 `json:"gamma,omitempty"`
+	LiveHeap float64 `json:"live_heap,omitempty"`
+
+	// HeapTargetActive, if not nil, restricts the point to operating
+	// points where the heap target is or isn't active.
+	HeapTargetActive *bool `json:"heap_target_active,omitempty"`
+
+	Kp float64 `json:"k_p"`
+	Ti float64 `json:"t_i"`
+}
+
+// validate checks that the schedule is non-empty, that each of its points
+// has positive gains, and that its points key on the same variables.
+func (cfg *ScheduledConfig) validate() error {
+	if len(cfg.Schedule) == 0 {
+		return fmt.Errorf("schedule must have at least one point")
+	}
+	var keyed *SchedulePoint
+	for i := range cfg.Schedule {
+		p := &cfg.Schedule[i]
+		if p.Kp <= 0 || p.Ti <= 0 {
+			return fmt.Errorf("schedule point %d: gains must be positive, got k_p=%v t_i=%v", i, p.Kp, p.Ti)
+		}
+		if p.Gamma < 0 || p.LiveHeap < 0 {
+			return fmt.Errorf("schedule point %d: operating point variables must not be negative", i)
+		}
+		if p.Gamma == 0 && p.LiveHeap == 0 {
+			continue
+		}
+		if keyed == nil {
+			keyed = p
+		} else if (p.Gamma > 0) != (keyed.Gamma > 0) || (p.LiveHeap > 0) != (keyed.LiveHeap > 0) {
+			return fmt.Errorf("schedule point %d: points must all specify the same operating point variables", i)
+		}
+	}
+	return nil
+}
+
+func NewScheduled(cfg *ScheduledConfig) *Scheduled {
+	c := &Scheduled{schedule: cfg.Schedule}
+	c.pi.PIConfig = cfg.PIConfig
+	if len(cfg.Schedule) > 0 {
+		c.pi.Kp = cfg.Schedule[0].Kp
+		c.pi.Ti = cfg.Schedule[0].Ti
+	}
+	return c
+}
+
+// Gains returns the PI controller's current gains.
+func (c *Scheduled) Gains() (kp, ti float64) {
+	return c.pi.Kp, c.pi.Ti
+}
+
+func (c *Scheduled) Next(input, setpoint float64) float64 {
+	return c.pi.Next(input, setpoint)
+}
+
+func (c *Scheduled) Observe(obs *Observation) float64 {
+	kp, ti, ok := c.interpolate(obs)
+	if ok && (kp != c.pi.Kp || ti != c.pi.Ti) {
+		// Keep the output the old gains would have produced.
+		rawOutput, _ := c.pi.output(obs.Input, obs.Setpoint)
+		c.pi.Kp, c.pi.Ti = kp, ti
+		c.pi.Track(obs.Input, obs.Setpoint, rawOutput)
+	}
+	return c.pi.Next(obs.Input, obs.Setpoint)
+}
+
+func (c *Scheduled) Track(input, setpoint, output float64) {
+	c.pi.Track(input, setpoint, output)
+}
+
+// interpolate returns the gains for obs's operating point, or false
+// if no point in the schedule applies to it.
+func (c *Scheduled) interpolate(obs *Observation) (kp, ti float64, ok bool) {
+	var sum float64
+	var fallback *SchedulePoint
+	for i := range c.schedule {
+		p := &c.schedule[i]
+		if p.HeapTargetActive != nil && *p.HeapTargetActive != obs.HeapTargetActive {
+			continue
+		}
+		var d float64
+		var n int
+		for _, v := range [][2]float64{{p.Gamma, obs.Gamma}, {p.LiveHeap, obs.LiveHeap}} {
+			if v[0] > 0 && v[1] > 0 {
+				l := math.Log(v[0] / v[1])
+				d += l * l
+				n++
+			}
+		}
+		if n == 0 {
+			// The point doesn't specify anything it could be
+			// scheduled on, so it only applies if no others do.
+			if fallback == nil {
+				fallback = p
+			}
+			continue
+		}
+		if d == 0 {
+			return p.Kp, p.Ti, true
+		}
+		kp += p.Kp / d
+		ti += p.Ti / d
+		sum += 1 / d
+	}
+	if sum == 0 {
+		if fallback == nil {
+			return 0, 0, false
+		}
+		return fallback.Kp, fallback.Ti, true
+	}
+	return kp / sum, ti / sum, true
+}
+
+func (c *Scheduled) Introspect() map[string]float64 {
+	m := c.pi.Introspect()
+	m["k_p"], m["t_i"] = c.Gains()
+	return m
+}
@@ -0,0 +1,77 @@
+package controller
+
+import "encoding/json"
+
+// SlewLimit limits a controller's output to [-Rate, Rate].
+//
+// go116 and go117 add their controller's output to the value they control
+// each cycle, so this limits how fast that value changes. If the limited
+// controller is Bumpless, it tracks the limited output, so that it doesn't
+// wind up while limited.
+type SlewLimit struct {
+	ctrl Controller
+	rate float64
+
+	// unlimited and limited are the last output
+	// before and after limiting.
+	unlimited, limited float64
+}
+
+type SlewLimitConfig struct {
+	// Rate is the largest change per cycle.
+	Rate float64 `json:"rate"`
+
+	// Controller is the configuration of the controller to limit,
+	// as for New.
+	Controller json.RawMessage `json:"controller"`
+}
+
+func NewSlewLimit(ctrl Controller, rate float64) *SlewLimit {
+	return &SlewLimit{ctrl: ctrl, rate: rate}
+}
+
+func (c *SlewLimit) Next(input, setpoint float64) float64 {
+	return c.limit(input, setpoint, c.ctrl.Next(input, setpoint))
+}
+
+// Observe passes obs to the limited controller if it's an Observer.
+func (c *SlewLimit) Observe(obs *Observation) float64 {
+	if o, ok := c.ctrl.(Observer); ok {
+		return c.limit(obs.Input, obs.Setpoint, o.Observe(obs))
+	}
+	return c.Next(obs.Input, obs.Setpoint)
+}
+
+func (c *SlewLimit) Track(input, setpoint, output float64) {
+	if b, ok := c.ctrl.(Bumpless); ok {
+		b.Track(input, setpoint, output)
+	}
+}
+
+func (c *SlewLimit) limit(input, setpoint, output float64) float64 {
+	c.unlimited = output
+	if output > c.rate {
+		output = c.rate
+	} else if output < -c.rate {
+		output = -c.rate
+	}
+	if output != c.unlimited {
+		c.Track(input, setpoint, output)
+	}
+	c.limited = output
+	return output
+}
+
+// Introspect returns the limited controller's signals, if it's an
+// Introspector, along with the output before and after limiting.
+func (c *SlewLimit) Introspect() map[string]float64 {
+	var m map[string]float64
+	if i, ok := c.ctrl.(Introspector); ok {
+		m = i.Introspect()
+	} else {
+		m = make(map[string]float64)
+	}
+	m["unlimited_output"] = c.unlimited
+	m["limited_output"] = c.limited
+	return m
+}
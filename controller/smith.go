@@ -0,0 +1,101 @@
+package controller
+
+import "encoding/json"
+
+// SmithPredictor compensates a controller for measurements that arrive
+// some number of cycles late.
+//
+// The controller drives an input that changes by Gain times its output each
+// cycle, like go117's R. The predictor adds the effect of the outputs that
+// aren't reflected in a delayed input yet, and passes the predicted current
+// input to the controller. Delays in the setpoint can't be compensated.
+//
+// The delay must match the pacer's: compensating for a delay that isn't
+// there destabilizes the loop. Pacers that provide an Observation say what
+// their delay is, which overrides the configured one.
+type SmithPredictor struct {
+	ctrl Controller
+	SmithPredictorConfig
+
+	// delay is the current measurement delay, and outputs are
+	// the last delay outputs, oldest first.
+	delay   int
+	outputs []float64
+
+	// predicted is the last predicted input.
+	predicted float64
+}
+
+type SmithPredictorConfig struct {
+	// Delay is the measurement delay in cycles, for pacers that
+	// don't provide an Observation.
+	Delay int `json:"delay,omitempty"`
+
+	// Gain is the plant model's gain from output to input.
+	Gain float64 `json:"gain"`
+
+	// Controller is the configuration of the controller to compensate,
+	// as for New.
+	Controller json.RawMessage `json:"controller"`
+}
+
+func NewSmithPredictor(ctrl Controller, cfg *SmithPredictorConfig) *SmithPredictor {
+	return &SmithPredictor{ctrl: ctrl, SmithPredictorConfig: *cfg, delay: cfg.Delay}
+}
+
+func (c *SmithPredictor) Next(input, setpoint float64) float64 {
+	return c.record(c.ctrl.Next(c.predict(input), setpoint))
+}
+
+// Observe passes obs to the compensated controller if it's an Observer,
+// and compensates for obs.MeasurementDelay.
+func (c *SmithPredictor) Observe(obs *Observation) float64 {
+	c.delay = obs.MeasurementDelay
+	o, ok := c.ctrl.(Observer)
+	if !ok {
+		return c.Next(obs.Input, obs.Setpoint)
+	}
+	pred := *obs
+	pred.Input = c.predict(obs.Input)
+	return c.record(o.Observe(&pred))
+}
+
+func (c *SmithPredictor) Track(input, setpoint, output float64) {
+	if b, ok := c.ctrl.(Bumpless); ok {
+		b.Track(c.predict(input), setpoint, output)
+	}
+}
+
+// predict predicts the current input from a delayed one.
+func (c *SmithPredictor) predict(input float64) float64 {
+	outputs := c.outputs
+	if len(outputs) > c.delay {
+		outputs = outputs[len(outputs)-c.delay:]
+	}
+	for _, u := range outputs {
+		input += c.Gain * u
+	}
+	c.predicted = input
+	return input
+}
+
+func (c *SmithPredictor) record(output float64) float64 {
+	c.outputs = append(c.outputs, output)
+	if len(c.outputs) > c.delay {
+		c.outputs = c.outputs[len(c.outputs)-c.delay:]
+	}
+	return output
+}
+
+// Introspect returns the compensated controller's signals, if it's an
+// Introspector, along with the predicted input.
+func (c *SmithPredictor) Introspect() map[string]float64 {
+	var m map[string]float64
+	if i, ok := c.ctrl.(Introspector); ok {
+		m = i.Introspect()
+	} else {
+		m = make(map[string]float64)
+	}
+	m["predicted_input"] = c.predicted
+	return m
+}
@@ -0,0 +1,17 @@
+// Package estimator contains estimators that filter noisy per-cycle
+// measurements before they reach a controller.
+package estimator
+
+type Estimator interface {
+	// Update incorporates a new measurement and returns the new estimate.
+	Update(measurement float64) float64
+}
+
+// VarianceEstimator is an Estimator that also knows how uncertain
+// its estimate is.
+type VarianceEstimator interface {
+	Estimator
+
+	// Variance returns the variance of the current estimate.
+	Variance() float64
+}
@@ -0,0 +1,55 @@
+package estimator
+
+// Kalman is a Kalman filter that models the estimated value as a random walk.
+type Kalman struct {
+	KalmanConfig
+	estimate float64
+	variance float64
+	started  bool
+}
+
+type KalmanConfig struct {
+	// ProcessNoise is the variance of the random walk per update,
+	// that is, how much the true value is expected to change.
+	ProcessNoise float64 `json:"process_noise"`
+
+	// MeasurementNoise is the variance of each measurement.
+	MeasurementNoise float64 `json:"measurement_noise"`
+
+	// InitialVariance is the variance of the first measurement, which
+	// is taken as the initial estimate. If zero, MeasurementNoise is used.
+	InitialVariance float64 `json:"initial_variance"`
+}
+
+func NewKalman(cfg *KalmanConfig) *Kalman {
+	return &Kalman{KalmanConfig: *cfg}
+}
+
+func (k *Kalman) Update(measurement float64) float64 {
+	if !k.started {
+		k.started = true
+		k.estimate = measurement
+		k.variance = k.InitialVariance
+		if k.variance == 0 {
+			k.variance = k.MeasurementNoise
+		}
+		return k.estimate
+	}
+
+	// Predict. The estimate doesn't change for a random walk,
+	// but it becomes less certain.
+	k.variance += k.ProcessNoise
+
+	// Correct.
+	gain := 1.0
+	if k.variance+k.MeasurementNoise > 0 {
+		gain = k.variance / (k.variance + k.MeasurementNoise)
+	}
+	k.estimate += gain * (measurement - k.estimate)
+	k.variance *= 1 - gain
+	return k.estimate
+}
+
+func (k *Kalman) Variance() float64 {
+	return k.variance
+}
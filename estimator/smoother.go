@@ -0,0 +1,124 @@
+package estimator
+
+import (
+	"fmt"
+	"sort"
+)
+
+type SmootherConfig struct {
+	// Type is the name of the smoother. See Smoothers.
+	Type string `json:"type"`
+
+	// Alpha is the weight of each new measurement for "ewma".
+	Alpha float64 `json:"alpha"`
+
+	// Window is the number of most recent measurements, including
+	// the newest, that "window-max" and "median" consider.
+	Window int `json:"window"`
+}
+
+var smoothers = map[string]func(*SmootherConfig) (Estimator, error){
+	"ewma": func(cfg *SmootherConfig) (Estimator, error) {
+		if cfg.Alpha <= 0 || cfg.Alpha > 1 {
+			return nil, fmt.Errorf("ewma alpha must be in (0, 1], got %v", cfg.Alpha)
+		}
+		return &EWMA{Alpha: cfg.Alpha}, nil
+	},
+	"window-max": func(cfg *SmootherConfig) (Estimator, error) {
+		if cfg.Window <= 0 {
+			return nil, fmt.Errorf("window-max window must be positive, got %d", cfg.Window)
+		}
+		return &WindowMax{window: window{size: cfg.Window}}, nil
+	},
+	"median": func(cfg *SmootherConfig) (Estimator, error) {
+		if cfg.Window <= 0 {
+			return nil, fmt.Errorf("median window must be positive, got %d", cfg.Window)
+		}
+		return &Median{window: window{size: cfg.Window}}, nil
+	},
+}
+
+func Smoothers() []string {
+	var s []string
+	for name := range smoothers {
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return s
+}
+
+// NewSmoother creates the smoother named by cfg.Type.
+func NewSmoother(cfg *SmootherConfig) (Estimator, error) {
+	f, ok := smoothers[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown smoother type %q", cfg.Type)
+	}
+	return f(cfg)
+}
+
+// EWMA is an exponentially-weighted moving average.
+type EWMA struct {
+	Alpha    float64
+	estimate float64
+	started  bool
+}
+
+func (e *EWMA) Update(measurement float64) float64 {
+	if !e.started {
+		e.started = true
+		e.estimate = measurement
+	} else {
+		e.estimate = e.Alpha*measurement + (1-e.Alpha)*e.estimate
+	}
+	return e.estimate
+}
+
+// WindowMax estimates the maximum over the most recent measurements,
+// like the Go 1.18 pacer's cons/mark estimator.
+type WindowMax struct {
+	window
+}
+
+func (w *WindowMax) Update(measurement float64) float64 {
+	w.push(measurement)
+	max := w.values[0]
+	for _, v := range w.values[1:] {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// Median estimates the median of the most recent measurements.
+type Median struct {
+	window
+	sorted []float64
+}
+
+func (m *Median) Update(measurement float64) float64 {
+	m.push(measurement)
+	m.sorted = append(m.sorted[:0], m.values...)
+	sort.Float64s(m.sorted)
+	n := len(m.sorted)
+	if n%2 == 1 {
+		return m.sorted[n/2]
+	}
+	return (m.sorted[n/2-1] + m.sorted[n/2]) / 2
+}
+
+// window holds the most recent measurements, in no particular order.
+type window struct {
+	size   int
+	values []float64
+	next   int
+}
+
+func (w *window) push(v float64) {
+	if len(w.values) < w.size {
+		w.values = append(w.values, v)
+		return
+	}
+	w.values[w.next] = v
+	w.next = (w.next + 1) % w.size
+}
@@ -0,0 +1,514 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+// Event types in the execution trace wire format used by Go 1.22 and later.
+// Only the events needed to reconstruct GC cycles are interpreted, but every
+// event needs to be known to skip over it.
+const (
+	evEventBatch          = 1
+	evStacks              = 2
+	evStrings             = 4
+	evString              = 5
+	evCPUSamples          = 6
+	evFrequency           = 8
+	evProcsChange         = 9
+	evProcStop            = 11
+	evGoStart             = 16
+	evGoDestroy           = 17
+	evGoStop              = 19
+	evGoBlock             = 20
+	evGoStatus            = 25
+	evSTWBegin            = 26
+	evSTWEnd              = 27
+	evGCBegin             = 29
+	evGCEnd               = 30
+	evGCMarkAssistBegin   = 35
+	evGCMarkAssistEnd     = 36
+	evHeapAlloc           = 37
+	evHeapGoal            = 38
+	evGoLabel             = 39
+	evGoSwitch            = 45
+	evGoSwitchDestroy     = 46
+	evGoStatusStack       = 48
+	evExperimentalBatch   = 49
+	evSync                = 50
+	evEndOfGeneration     = 52
+	execTraceMaxBatchSize = 64 << 10
+)
+
+// execTraceArgs is the number of arguments, including the timestamp delta,
+// of each event that may appear in a regular event batch, indexed by type.
+var execTraceArgs = [...]int{
+	9:  3, // ProcsChange
+	10: 3, // ProcStart
+	11: 1, // ProcStop
+	12: 4, // ProcSteal
+	13: 3, // ProcStatus
+	14: 4, // GoCreate
+	15: 2, // GoCreateSyscall
+	16: 3, // GoStart
+	17: 1, // GoDestroy
+	18: 1, // GoDestroySyscall
+	19: 3, // GoStop
+	20: 3, // GoBlock
+	21: 4, // GoUnblock
+	22: 3, // GoSyscallBegin
+	23: 1, // GoSyscallEnd
+	24: 1, // GoSyscallEndBlocked
+	25: 4, // GoStatus
+	26: 3, // STWBegin
+	27: 1, // STWEnd
+	28: 2, // GCActive
+	29: 3, // GCBegin
+	30: 2, // GCEnd
+	31: 2, // GCSweepActive
+	32: 2, // GCSweepBegin
+	33: 3, // GCSweepEnd
+	34: 2, // GCMarkAssistActive
+	35: 2, // GCMarkAssistBegin
+	36: 1, // GCMarkAssistEnd
+	37: 2, // HeapAlloc
+	38: 2, // HeapGoal
+	39: 2, // GoLabel
+	40: 5, // UserTaskBegin
+	41: 3, // UserTaskEnd
+	42: 4, // UserRegionBegin
+	43: 4, // UserRegionEnd
+	44: 5, // UserLog
+	45: 3, // GoSwitch
+	46: 3, // GoSwitchDestroy
+	47: 4, // GoCreateBlocked
+	48: 5, // GoStatusStack
+	51: 4, // ClockSnapshot
+}
+
+// goRunning is the goroutine status of a running goroutine in GoStatus events.
+const goRunning = 2
+
+// ExecTrace infers a scenario from a runtime/trace execution trace read from r.
+// Only traces produced by Go 1.22 and later are supported.
+//
+// Execution traces don't include scan work, stacks, or globals, so the live
+// heap is assumed to be entirely scannable, but the timing of GC cycles and
+// of the GC's CPU time is much more precise than GODEBUG=gctrace=1 output.
+//
+// ExecTrace also returns the GC CPU utilization measured during the mark phase
+// of each cycle, which is comparable to simulation.Result.ActualGCUtilization.
+func ExecTrace(r io.Reader) (scenario.Execution, []float64, error) {
+	ms, err := readExecTrace(r)
+	if err != nil {
+		return scenario.Execution{}, nil, err
+	}
+	ex, err := infer(ms)
+	if err != nil {
+		return scenario.Execution{}, nil, err
+	}
+	u := make([]float64, len(ms))
+	for i := range ms {
+		u[i] = ms[i].utilization()
+	}
+	return ex, u, nil
+}
+
+// readExecTrace reads the GC cycles in an execution trace.
+func readExecTrace(r io.Reader) ([]measurement, error) {
+	br := bufio.NewReader(r)
+	var hdr [16]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("reading header: %v", err)
+	}
+	var version int
+	if _, err := fmt.Sscanf(string(hdr[:]), "go 1.%d trace\x00\x00\x00", &version); err != nil {
+		return nil, fmt.Errorf("not a Go execution trace")
+	}
+	if version < 22 {
+		return nil, fmt.Errorf("unsupported execution trace version go 1.%d: need Go 1.22 or later", version)
+	}
+	t := execTrace{
+		strings:   make(map[uint64]map[uint64]string),
+		assisting: make(map[uint64]bool),
+	}
+	if err := t.read(br); err != nil {
+		return nil, err
+	}
+	return t.measure()
+}
+
+// IsExecTrace reports whether b, the first few bytes of a file, looks like
+// the start of an execution trace.
+func IsExecTrace(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("go 1.")) && bytes.Contains(b, []byte(" trace\x00"))
+}
+
+// execBatch is a batch of events in an execution trace.
+type execBatch struct {
+	gen  uint64
+	m    uint64
+	time uint64
+	data []byte
+}
+
+// execEvent is a GC-wide event, with its time in nanoseconds.
+type execEvent struct {
+	time float64
+	typ  byte
+	arg  uint64
+	str  string
+}
+
+// execInterval is a span of GC CPU time on a single thread.
+type execInterval struct {
+	start, end float64
+	idle       bool
+}
+
+// execThread is the state of a thread (M) in the runtime.
+type execThread struct {
+	g        uint64
+	worker   bool
+	idle     bool
+	start    float64
+	assisted bool
+}
+
+type execTrace struct {
+	batches []execBatch
+
+	// strings are the string tables for each generation.
+	strings map[uint64]map[uint64]string
+
+	// freq is the number of nanoseconds per timestamp unit.
+	freq float64
+
+	events    []execEvent
+	intervals []execInterval
+
+	// assisting is the set of goroutines that stopped running
+	// in the middle of an assist.
+	assisting map[uint64]bool
+}
+
+// read reads all the batches in a trace.
+func (t *execTrace) read(r *bufio.Reader) error {
+	for {
+		typ, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		switch typ {
+		case evEndOfGeneration:
+			continue
+		case evEventBatch, evExperimentalBatch:
+		default:
+			return fmt.Errorf("expected batch, got event type %d", typ)
+		}
+		if typ == evExperimentalBatch {
+			// Skip the experiment ID.
+			if _, err := r.ReadByte(); err != nil {
+				return err
+			}
+		}
+		var hdr [4]uint64
+		for i := range hdr {
+			if hdr[i], err = binary.ReadUvarint(r); err != nil {
+				return fmt.Errorf("reading batch header: %v", err)
+			}
+		}
+		size := hdr[3]
+		if size > execTraceMaxBatchSize {
+			return fmt.Errorf("invalid batch size %d", size)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("reading batch: %v", err)
+		}
+		if typ == evExperimentalBatch || len(data) == 0 {
+			continue
+		}
+		b := execBatch{gen: hdr[0], m: hdr[1], time: hdr[2], data: data}
+		switch data[0] {
+		case evStrings:
+			if err := t.readStrings(&b); err != nil {
+				return err
+			}
+		case evSync, evFrequency:
+			if err := t.readFrequency(&b); err != nil {
+				return err
+			}
+		case evStacks, evCPUSamples:
+		default:
+			t.batches = append(t.batches, b)
+		}
+	}
+	if t.freq == 0 {
+		return fmt.Errorf("no frequency event found")
+	}
+	return nil
+}
+
+func (t *execTrace) readStrings(b *execBatch) error {
+	table := t.strings[b.gen]
+	if table == nil {
+		table = make(map[uint64]string)
+		t.strings[b.gen] = table
+	}
+	r := bytes.NewReader(b.data[1:])
+	for r.Len() > 0 {
+		if typ, _ := r.ReadByte(); typ != evString {
+			return fmt.Errorf("expected string event, got event type %d", typ)
+		}
+		id, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		if n > uint64(r.Len()) {
+			return fmt.Errorf("invalid string size %d", n)
+		}
+		s := make([]byte, n)
+		r.Read(s)
+		table[id] = string(s)
+	}
+	return nil
+}
+
+func (t *execTrace) readFrequency(b *execBatch) error {
+	data := b.data
+	if data[0] == evSync {
+		data = data[1:]
+	}
+	if len(data) == 0 || data[0] != evFrequency {
+		// A sync batch without a frequency.
+		return nil
+	}
+	f, n := binary.Uvarint(data[1:])
+	if n <= 0 || f == 0 {
+		return fmt.Errorf("invalid frequency event")
+	}
+	if t.freq == 0 {
+		t.freq = 1e9 / float64(f)
+	}
+	return nil
+}
+
+// measure reconstructs the GC cycles in the trace.
+func (t *execTrace) measure() ([]measurement, error) {
+	threads := make(map[uint64]*execThread)
+	for i := range t.batches {
+		if err := t.scan(&t.batches[i], threads); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(t.events, func(i, j int) bool {
+		return t.events[i].time < t.events[j].time
+	})
+
+	// Walk through the GC-wide events in order.
+	//
+	// Each cycle starts with a GCBegin event, after which the world is
+	// stopped for sweep termination. When the world is restarted, the
+	// concurrent mark phase begins, and lasts until the world is stopped
+	// for mark termination. Marking is then finished, and the heap is
+	// reset to the marked heap before the GCEnd event.
+	var (
+		ms                  []measurement
+		cur                 *measurement
+		stwKind             string
+		heapAlloc, heapGoal uint64
+		procs               int
+		markEnd             float64
+	)
+	for _, ev := range t.events {
+		switch ev.typ {
+		case evProcsChange:
+			procs = int(ev.arg)
+		case evHeapAlloc:
+			heapAlloc = ev.arg
+			if cur != nil && markEnd != 0 {
+				cur.heapLive = heapAlloc
+			}
+		case evHeapGoal:
+			heapGoal = ev.arg
+		case evGCBegin:
+			cur = &measurement{heapStart: heapAlloc, heapGoal: heapGoal, procs: procs}
+			markEnd = 0
+		case evSTWBegin:
+			stwKind = ev.str
+			if cur != nil && stwKind == "GC mark termination" && cur.start != 0 {
+				markEnd = ev.time
+				cur.heapEnd = heapAlloc
+			}
+		case evSTWEnd:
+			if cur != nil && stwKind == "GC sweep termination" {
+				cur.start = ev.time
+			} else if cur != nil && stwKind == "GC mark termination" {
+				// Mark termination may find more work and restart
+				// the world.
+				if cur.heapLive == 0 {
+					markEnd = 0
+				}
+			}
+			stwKind = ""
+		case evGCEnd:
+			if cur != nil && cur.start != 0 && markEnd > cur.start && cur.heapLive != 0 {
+				cur.markTime = markEnd - cur.start
+				ms = append(ms, *cur)
+			}
+			cur = nil
+		}
+	}
+
+	// Attribute GC CPU time to the cycle it happened in.
+	for _, iv := range t.intervals {
+		i := sort.Search(len(ms), func(i int) bool {
+			return ms[i].start+ms[i].markTime > iv.start
+		})
+		for ; i < len(ms) && ms[i].start < iv.end; i++ {
+			m := &ms[i]
+			d := minFloat(iv.end, m.start+m.markTime) - maxFloat(iv.start, m.start)
+			if d <= 0 {
+				continue
+			}
+			m.gcCPUTime += d
+			if iv.idle {
+				m.idleCPUTime += d
+			}
+		}
+	}
+	for i := range ms {
+		if ms[i].procs == 0 {
+			return nil, fmt.Errorf("GOMAXPROCS not found in trace")
+		}
+	}
+	return ms, nil
+}
+
+// scan reads the events in a batch, collecting GC-wide events and
+// the GC CPU time used by the batch's thread.
+func (t *execTrace) scan(b *execBatch, threads map[uint64]*execThread) error {
+	thread := func(m uint64) *execThread {
+		th := threads[m]
+		if th == nil {
+			th = new(execThread)
+			threads[m] = th
+		}
+		return th
+	}
+	th := thread(b.m)
+	ts := b.time
+	var args [5]uint64
+	for data := b.data; len(data) > 0; {
+		typ := data[0]
+		if int(typ) >= len(execTraceArgs) || execTraceArgs[typ] == 0 {
+			return fmt.Errorf("unexpected event type %d in batch", typ)
+		}
+		n := 1
+		for i := 0; i < execTraceArgs[typ]; i++ {
+			v, nb := binary.Uvarint(data[n:])
+			if nb <= 0 {
+				return fmt.Errorf("invalid event argument")
+			}
+			args[i] = v
+			n += nb
+		}
+		data = data[n:]
+		ts += args[0]
+		now := float64(ts) * t.freq
+
+		switch typ {
+		case evGoStart, evGoSwitch, evGoSwitchDestroy:
+			t.stop(th, now)
+			th.g = args[1]
+			t.resume(th, now)
+		case evGoStatus, evGoStatusStack:
+			if args[3] == goRunning {
+				if o := thread(args[2]); o.g != args[1] {
+					t.stop(o, now)
+					o.g = args[1]
+				}
+			}
+		case evGoStop, evGoBlock, evGoDestroy, evProcStop:
+			t.stop(th, now)
+			if typ != evProcStop {
+				th.g = 0
+			}
+		case evGoLabel:
+			label := t.strings[b.gen][args[1]]
+			if strings.HasPrefix(label, "GC (") {
+				th.worker = true
+				th.idle = label == "GC (idle)"
+				th.start = now
+			}
+		case evGCMarkAssistBegin:
+			th.assisted = true
+			th.start = now
+		case evGCMarkAssistEnd:
+			if th.assisted {
+				t.intervals = append(t.intervals, execInterval{start: th.start, end: now})
+			}
+			th.assisted = false
+			delete(t.assisting, th.g)
+		case evSTWBegin:
+			t.events = append(t.events, execEvent{time: now, typ: typ, str: t.strings[b.gen][args[1]]})
+		case evProcsChange, evHeapAlloc, evHeapGoal, evGCBegin, evGCEnd, evSTWEnd:
+			t.events = append(t.events, execEvent{time: now, typ: typ, arg: args[1]})
+		}
+	}
+	return nil
+}
+
+// stop ends any GC work by the goroutine running on th.
+func (t *execTrace) stop(th *execThread, now float64) {
+	if th.worker {
+		t.intervals = append(t.intervals, execInterval{start: th.start, end: now, idle: th.idle})
+		th.worker = false
+	}
+	if th.assisted {
+		// The assist blocked, or was preempted. It'll continue when
+		// the goroutine runs again.
+		t.intervals = append(t.intervals, execInterval{start: th.start, end: now})
+		th.assisted = false
+		if th.g != 0 {
+			t.assisting[th.g] = true
+		}
+	}
+}
+
+// resume continues an assist by the goroutine that just started running on th.
+func (t *execTrace) resume(th *execThread, now float64) {
+	if t.assisting[th.g] {
+		delete(t.assisting, th.g)
+		th.assisted = true
+		th.start = now
+	}
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
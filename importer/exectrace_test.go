@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string, read func(io.Reader) ([]measurement, error)) []measurement {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ms, err := read(f)
+	if err != nil {
+		t.Fatalf("reading %s: %v", name, err)
+	}
+	return ms
+}
+
+func within(got, want, frac float64) bool {
+	return math.Abs(got-want) <= frac*want
+}
+
+// TestExecTrace checks the GC cycles read from an execution trace against
+// the gctrace output of the same run.
+func TestExecTrace(t *testing.T) {
+	const cycles = 13
+	got := readTestdata(t, "small.trace", readExecTrace)
+	want := readTestdata(t, "small.gctrace", readGCTrace)
+	if len(want) != cycles {
+		t.Fatalf("found %d cycles in gctrace, want %d", len(want), cycles)
+	}
+	if len(got) != cycles {
+		t.Fatalf("found %d cycles in execution trace, want %d", len(got), cycles)
+	}
+	for i := range got {
+		g, w := &got[i], &want[i]
+		if g.procs != w.procs {
+			t.Errorf("cycle %d: GOMAXPROCS = %d, want %d", i+1, g.procs, w.procs)
+		}
+		// gctrace reports the live heap and goal in truncated MB.
+		if g.heapLive>>20 != w.heapLive>>20 {
+			t.Errorf("cycle %d: live heap = %d B, want %d MB", i+1, g.heapLive, w.heapLive>>20)
+		}
+		if g.heapGoal>>20 != w.heapGoal>>20 {
+			t.Errorf("cycle %d: heap goal = %d B, want %d MB", i+1, g.heapGoal, w.heapGoal>>20)
+		}
+		// Heap samples in the execution trace may lag behind slightly.
+		if !within(float64(g.heapStart), float64(w.heapStart), 0.02) {
+			t.Errorf("cycle %d: heap at trigger = %d B, want %d B", i+1, g.heapStart, w.heapStart)
+		}
+		if !within(float64(g.heapEnd), float64(w.heapEnd), 0.02) {
+			t.Errorf("cycle %d: heap at mark termination = %d B, want %d B", i+1, g.heapEnd, w.heapEnd)
+		}
+		// gctrace reports the mark phase with two significant digits.
+		if !within(g.markTime, w.markTime, 0.1) {
+			t.Errorf("cycle %d: mark phase took %f ms, want %f ms", i+1, g.markTime/1e6, w.markTime/1e6)
+		}
+		if u := g.utilization(); u <= 0 || u > 1 {
+			t.Errorf("cycle %d: GC CPU utilization = %f, want in (0, 1]", i+1, u)
+		}
+		if i > 0 && g.start <= got[i-1].start+got[i-1].markTime {
+			t.Errorf("cycle %d: starts at %f ms, before the previous mark phase ended", i+1, g.start/1e6)
+		}
+	}
+
+	f, err := os.Open(filepath.Join("testdata", "small.trace"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ex, u, err := ExecTrace(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ex.Cycles) != cycles || len(u) != cycles {
+		t.Fatalf("ExecTrace returned %d cycles and %d utilizations, want %d", len(ex.Cycles), len(u), cycles)
+	}
+	// The program ran with GOGC=100.
+	if g := ex.Globals.Gamma; g < 2 || g > 2.1 {
+		t.Errorf("Gamma = %f, want about 2", g)
+	}
+}
+
+func TestExecTraceNotATrace(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "small.gctrace"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, _, err := ExecTrace(f); err == nil {
+		t.Error("ExecTrace accepted gctrace output")
+	}
+}
@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+var (
+	// gctraceRE matches a line of GODEBUG=gctrace=1 output. Stacks and
+	// globals are only reported by Go 1.18 and later.
+	gctraceRE = regexp.MustCompile(`^gc (\d+) @([\d.]+)s (\d+)%: ` +
+		`([\d.]+)\+([\d.]+)\+([\d.]+) ms clock, ` +
+		`([\d.]+)\+([\d.]+)/([\d.]+)/([\d.]+)\+([\d.]+) ms cpu, ` +
+		`(\d+)->(\d+)->(\d+) MB, (\d+) MB goal, ` +
+		`(?:(\d+) MB stacks, (\d+) MB globals, )?` +
+		`(\d+) P`)
+
+	// pacertraceRE matches the line of GODEBUG=gcpacertrace=1 output printed
+	// at the end of each mark phase by Go 1.18 and later.
+	pacertraceRE = regexp.MustCompile(`^pacer: (\d+)% CPU \((\d+) exp\.\) ` +
+		`for (\d+)\+(\d+)\+(\d+) B work \((\d+) B exp\.\) ` +
+		`in (\d+) B -> (\d+) B`)
+)
+
+// GCTrace infers a scenario from GODEBUG=gctrace=1 output read from r.
+//
+// If the output also contains GODEBUG=gcpacertrace=1 output, the more precise
+// heap sizes and scan work it reports are used. Any other lines are ignored.
+func GCTrace(r io.Reader) (scenario.Execution, error) {
+	ms, err := readGCTrace(r)
+	if err != nil {
+		return scenario.Execution{}, err
+	}
+	return infer(ms)
+}
+
+// readGCTrace reads the GC cycles in gctrace output.
+func readGCTrace(r io.Reader) ([]measurement, error) {
+	var ms []measurement
+	var pacer []string
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		if m := pacertraceRE.FindStringSubmatch(s.Text()); m != nil {
+			pacer = m
+			continue
+		}
+		m := gctraceRE.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		meas, err := parseGCTrace(m, pacer)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		ms = append(ms, meas)
+		pacer = nil
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+func parseGCTrace(gc, pacer []string) (measurement, error) {
+	var p parser
+	m := measurement{
+		start:       p.float(gc[2]) * 1e9,
+		markTime:    p.float(gc[5]) * 1e6,
+		gcCPUTime:   (p.float(gc[8]) + p.float(gc[9]) + p.float(gc[10])) * 1e6,
+		idleCPUTime: p.float(gc[10]) * 1e6,
+		heapStart:   p.uint(gc[12]) << 20,
+		heapEnd:     p.uint(gc[13]) << 20,
+		heapLive:    p.uint(gc[14]) << 20,
+		heapGoal:    p.uint(gc[15]) << 20,
+		procs:       int(p.uint(gc[18])),
+	}
+	if gc[16] != "" {
+		m.stackBytes = p.uint(gc[16]) << 20
+		m.globalsBytes = p.uint(gc[17]) << 20
+	}
+	if pacer != nil {
+		m.heapScanWork = p.uint(pacer[3])
+		m.stackBytes = p.uint(pacer[4])
+		m.globalsBytes = p.uint(pacer[5])
+		m.heapStart = p.uint(pacer[7])
+		m.heapEnd = p.uint(pacer[8])
+		m.hasScanWork = true
+	}
+	return m, p.err
+}
+
+// parser parses numbers, remembering the first error.
+type parser struct {
+	err error
+}
+
+func (p *parser) float(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	return v
+}
+
+func (p *parser) uint(s string) uint64 {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestGCTrace(t *testing.T) {
+	const trace = `gc 1 @0.012s 2%: 0.015+1.2+0.003 ms clock, 0.12+0.30/1.1/2.4+0.024 ms cpu, 4->4->1 MB, 5 MB goal, 8 P
+some other output
+gc 2 @0.020s 3%: 0.010+2.5+0.004 ms clock, 0.080+0.50/2.0/0+0.032 ms cpu, 4->6->3 MB, 5 MB goal, 1 MB stacks, 2 MB globals, 4 P
+pacer: assist ratio=0.5690318391956605 (scan 1 MB in 9->11 MB) workers=0+0.25
+pacer: 75% CPU (25 exp.) for 886104+4392+151754 B work (1002658 B exp.) in 10101816 B -> 11928632 B (∆goal 64774, cons/mark 2.3287548208316635)
+pacer: sweep done at heap size 7MB; allocated 2MB during sweep; swept 1499 pages at 0 pages/byte
+gc 3 @0.029s 25%: 0.22+4.1+0.032 ms clock, 0.22+4.3/0.59/0.097+0.032 ms cpu, 9->11->4 MB, 11 MB goal, 0 MB stacks, 0 MB globals, 2 P
+gc 4 @0.035s 25%: 0.20+4.0+0.030 ms clock, 0.20+4.0/0.50/0+0.030 ms cpu, 9->11->5 MB, 10 MB goal, 0 MB stacks, 0 MB globals, 2 P
+`
+	want := []measurement{
+		// Before Go 1.18, without stacks and globals.
+		{
+			start: 12e6, markTime: 1.2e6,
+			gcCPUTime: 3.8e6, idleCPUTime: 2.4e6, procs: 8,
+			heapStart: 4 << 20, heapEnd: 4 << 20, heapLive: 1 << 20, heapGoal: 5 << 20,
+		},
+		// With stacks and globals.
+		{
+			start: 20e6, markTime: 2.5e6,
+			gcCPUTime: 2.5e6, procs: 4,
+			heapStart: 4 << 20, heapEnd: 6 << 20, heapLive: 3 << 20, heapGoal: 5 << 20,
+			stackBytes: 1 << 20, globalsBytes: 2 << 20,
+		},
+		// With GODEBUG=gcpacertrace=1 output, which is more precise.
+		{
+			start: 29e6, markTime: 4.1e6,
+			gcCPUTime: 4.987e6, idleCPUTime: 0.097e6, procs: 2,
+			heapStart: 10101816, heapEnd: 11928632, heapLive: 4 << 20, heapGoal: 11 << 20,
+			heapScanWork: 886104, stackBytes: 4392, globalsBytes: 151754, hasScanWork: true,
+		},
+		// The pacer trace only applies to the cycle it precedes.
+		{
+			start: 35e6, markTime: 4e6,
+			gcCPUTime: 4.5e6, procs: 2,
+			heapStart: 9 << 20, heapEnd: 11 << 20, heapLive: 5 << 20, heapGoal: 10 << 20,
+		},
+	}
+	got, err := readGCTrace(strings.NewReader(trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("found %d cycles, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		for _, f := range [][2]float64{
+			{g.start, w.start},
+			{g.markTime, w.markTime},
+			{g.gcCPUTime, w.gcCPUTime},
+			{g.idleCPUTime, w.idleCPUTime},
+		} {
+			if math.Abs(f[0]-f[1]) > 1e-6*f[1] {
+				t.Errorf("cycle %d: got %+v, want %+v", i+1, g, w)
+				break
+			}
+		}
+		g.start, g.markTime, g.gcCPUTime, g.idleCPUTime = w.start, w.markTime, w.gcCPUTime, w.idleCPUTime
+		if g != w {
+			t.Errorf("cycle %d: got %+v, want %+v", i+1, g, w)
+		}
+	}
+}
+
+func TestGCTraceBadLine(t *testing.T) {
+	const trace = "gc 1 @0.012s 2%: 0.015+1.2+0.003 ms clock, 0.12+0.30/1.1/2.4+0.024 ms cpu, 99999999999999999999->4->1 MB, 5 MB goal, 8 P\n"
+	if _, err := readGCTrace(strings.NewReader(trace)); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("got error %v, want an error for line 1", err)
+	}
+}
+
+func TestGCTraceEmpty(t *testing.T) {
+	if _, err := GCTrace(strings.NewReader("no GC here\n")); err == nil {
+		t.Error("GCTrace succeeded without any GC cycles")
+	}
+}
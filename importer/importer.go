@@ -0,0 +1,142 @@
+// Package importer infers scenarios from traces of real Go programs.
+package importer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+// measurement is what was observed of a single GC cycle in a real program.
+type measurement struct {
+	// start is the time the GC cycle started, and markTime is the
+	// wall-clock duration of the mark phase, both in nanoseconds.
+	start    float64
+	markTime float64
+
+	// gcCPUTime is the total CPU time used by the GC during the mark phase,
+	// in nanoseconds, of which idleCPUTime was used by idle mark workers.
+	// procs is GOMAXPROCS.
+	gcCPUTime   float64
+	idleCPUTime float64
+	procs       int
+
+	// Heap sizes in bytes at the start of the cycle, the end of the cycle,
+	// after marking (i.e. the live heap), and the heap goal.
+	heapStart uint64
+	heapEnd   uint64
+	heapLive  uint64
+	heapGoal  uint64
+
+	// Scan work done in the cycle, in bytes. heapScanWork is only valid
+	// if hasScanWork is true.
+	heapScanWork uint64
+	stackBytes   uint64
+	globalsBytes uint64
+	hasScanWork  bool
+}
+
+// utilization returns the GC's CPU utilization during the mark phase.
+//
+// Idle mark workers only soak up CPU time the mutator wasn't using,
+// and the simulators don't model them, so they don't count.
+func (m *measurement) utilization() float64 {
+	if m.markTime <= 0 || m.procs <= 0 {
+		return 0
+	}
+	return (m.gcCPUTime - m.idleCPUTime) / (m.markTime * float64(m.procs))
+}
+
+// infer builds a scenario from a sequence of consecutive GC cycles.
+//
+// Allocation and scan rates are in bytes per nanosecond of wall-clock time,
+// with all procs available, the same units pacer-sim uses.
+func infer(ms []measurement) (scenario.Execution, error) {
+	if len(ms) == 0 {
+		return scenario.Execution{}, fmt.Errorf("no GC cycles found")
+	}
+	var ex scenario.Execution
+	var gammas []float64
+	for i := range ms {
+		m := &ms[i]
+		allocBlack := float64(m.heapEnd) - float64(m.heapStart)
+
+		u := m.utilization()
+
+		// The allocation rate is best measured between GC cycles, when
+		// the mutator has the whole CPU. For the first cycle, fall back
+		// to what was allocated during the mark phase.
+		var allocRate float64
+		if i > 0 {
+			prev := &ms[i-1]
+			if dt := m.start - (prev.start + prev.markTime); dt > 0 && m.heapStart > prev.heapLive {
+				allocRate = float64(m.heapStart-prev.heapLive) / dt
+			}
+		}
+		if allocRate == 0 && u < 1 && m.markTime > 0 {
+			allocRate = allocBlack / ((1 - u) * m.markTime)
+		}
+
+		// The scan rate is the rate of scan work per unit of GC CPU time,
+		// normalized to the whole CPU.
+		heapScanWork := m.heapScanWork
+		if !m.hasScanWork {
+			// Assume everything that survived was scannable.
+			heapScanWork = m.heapLive
+		}
+		var scanRate float64
+		if m.gcCPUTime > 0 {
+			scanWork := float64(heapScanWork + m.stackBytes + m.globalsBytes)
+			scanRate = scanWork / (m.gcCPUTime / float64(m.procs))
+		}
+
+		// The simulators compute the live heap as
+		//
+		// live = (liveLast - allocBlackLast) * growthRate + allocBlack
+		//
+		// so back out the growth rate from that.
+		growthRate := 1.0
+		if i > 0 {
+			prev := &ms[i-1]
+			prevAllocBlack := float64(prev.heapEnd) - float64(prev.heapStart)
+			cur := float64(m.heapLive) - allocBlack
+			last := float64(prev.heapLive) - prevAllocBlack
+			if cur > 0 && last > 0 {
+				growthRate = cur / last
+			} else if prev.heapLive > 0 {
+				growthRate = float64(m.heapLive) / float64(prev.heapLive)
+			}
+			if prev.heapLive > 0 {
+				gammas = append(gammas, float64(m.heapGoal)/float64(prev.heapLive))
+			}
+		} else {
+			initial := float64(m.heapLive) - allocBlack
+			if initial <= 0 {
+				initial = float64(m.heapLive)
+			}
+			ex.Globals.InitialHeap = uint64(initial)
+		}
+
+		if m.globalsBytes > ex.Globals.GlobalsBytes {
+			ex.Globals.GlobalsBytes = m.globalsBytes
+		}
+		ex.Cycles = append(ex.Cycles, scenario.Cycle{
+			AllocRate:       allocRate,
+			ScanRate:        scanRate,
+			GrowthRate:      growthRate,
+			ScannableFrac:   1,
+			StackBytes:      m.stackBytes,
+			HeapTargetBytes: -1,
+		})
+	}
+
+	// Use the median goal/live ratio, which is robust to cycles where
+	// the heap goal was set by the heap minimum.
+	ex.Globals.Gamma = 2
+	if len(gammas) > 0 {
+		sort.Float64s(gammas)
+		ex.Globals.Gamma = gammas[len(gammas)/2]
+	}
+	return ex, nil
+}
@@ -0,0 +1,48 @@
+// This program generates small.trace and small.gctrace:
+//
+//	GOMAXPROCS=2 GODEBUG=gctrace=1,gcpacertrace=1 go run small.go small.trace 2> small.gctrace
+//
+// The execution trace needs Go 1.22 or later.
+package main
+
+import (
+	"os"
+	"runtime/trace"
+	"sync"
+)
+
+type node struct {
+	next *node
+	buf  [64]byte
+}
+
+var sink []*node
+
+func main() {
+	f, err := os.Create(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+	if err := trace.Start(f); err != nil {
+		panic(err)
+	}
+	sink = make([]*node, 1<<16)
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1<<19; i++ {
+				n := &node{}
+				if i%16 == 0 {
+					sink[(i/16+w*977)%len(sink)] = n
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	trace.Stop()
+	if err := f.Close(); err != nil {
+		panic(err)
+	}
+}
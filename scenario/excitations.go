@@ -0,0 +1,103 @@
+package scenario
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mknyszek/pacer-model/scenario/stream"
+)
+
+// Excitation is a canonical scenario for analyzing the closed-loop response
+// of a pacer. The scenario is steady except for a single step or impulse in
+// one of its inputs at cycle Onset.
+type Excitation struct {
+	Execution
+
+	// Onset is the first cycle affected by the excitation.
+	Onset int `json:"onset"`
+
+	// Impulse is true if the input returns to its original value
+	// after the excitation, and false if it stays at its new value.
+	Impulse bool `json:"impulse"`
+}
+
+// excitationOnset leaves enough time for the pacer to settle after
+// the initial ramp in growth rate.
+const excitationOnset = 50
+
+func Excite(name string) (Excitation, error) {
+	e, ok := excitations[name]
+	if !ok {
+		return Excitation{}, fmt.Errorf("excitation %q not found", name)
+	}
+	spec, impulse := e()
+	x, err := Build(spec)
+	if err != nil {
+		return Excitation{}, err
+	}
+	return Excitation{
+		Execution: x,
+		Onset:     excitationOnset,
+		Impulse:   impulse,
+	}, nil
+}
+
+func Excitations() []string {
+	var s []string
+	for name := range excitations {
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return s
+}
+
+var excitations = map[string]func() (spec Spec, impulse bool){
+	"alloc-step": func() (Spec, bool) {
+		return Spec{
+			Globals: Globals{
+				Gamma:        2,
+				GlobalsBytes: 32 << 10,
+				InitialHeap:  2 << 20,
+			},
+			AllocRate:       stream.Constant(1.0).Mix(stream.Step(1.0, excitationOnset)),
+			ScanRate:        stream.Constant(31.0),
+			GrowthRate:      stream.Constant(2.0).Mix(stream.Ramp(-1.0, 8)),
+			ScannableFrac:   stream.Constant(1.0),
+			StackBytes:      stream.Constant(8192),
+			HeapTargetBytes: stream.Constant(-1),
+			Length:          150,
+		}, false
+	},
+	"growth-impulse": func() (Spec, bool) {
+		return Spec{
+			Globals: Globals{
+				Gamma:        2,
+				GlobalsBytes: 32 << 10,
+				InitialHeap:  2 << 20,
+			},
+			AllocRate:       stream.Constant(1.0),
+			ScanRate:        stream.Constant(31.0),
+			GrowthRate:      stream.Constant(2.0).Mix(stream.Ramp(-1.0, 8), stream.Unit(0.5).Delay(excitationOnset)),
+			ScannableFrac:   stream.Constant(1.0),
+			StackBytes:      stream.Constant(8192),
+			HeapTargetBytes: stream.Constant(-1),
+			Length:          150,
+		}, true
+	},
+	"heap-target-step": func() (Spec, bool) {
+		return Spec{
+			Globals: Globals{
+				Gamma:        2,
+				GlobalsBytes: 32 << 10,
+				InitialHeap:  2 << 20,
+			},
+			AllocRate:       stream.Constant(1.0),
+			ScanRate:        stream.Constant(31.0),
+			GrowthRate:      stream.Constant(2.0).Mix(stream.Ramp(-1.0, 8)),
+			ScannableFrac:   stream.Constant(1.0),
+			StackBytes:      stream.Constant(8192),
+			HeapTargetBytes: stream.Constant(-1).Mix(stream.Step((256<<20)+1, excitationOnset)),
+			Length:          150,
+		}, false
+	},
+}
@@ -1,5 +1,7 @@
 package scenario
 
+import "encoding/json"
+
 type Execution struct {
 	Cycles  []Cycle `json:"cycles"`
 	Globals Globals `json:"global"`
@@ -12,10 +14,25 @@ type Cycle struct {
 	ScannableFrac   float64 `json:"scannable_frac"`
 	StackBytes      uint64  `json:"stack_bytes"`
 	HeapTargetBytes int64   `json:"heap_target"`
+
+	// Controller, if not empty, is the configuration of a controller, as
+	// for controller.New, that replaces the pacer's controller at the
+	// start of this cycle, like rolling out a new pacer configuration to
+	// a live process. Where possible, the new controller picks up where
+	// the old one left off. Pacers without a controller ignore it.
+	Controller json.RawMessage `json:"controller,omitempty"`
 }
 
 type Globals struct {
 	Gamma        float64 `json:"gamma"`
 	GlobalsBytes uint64  `json:"globals_bytes"`
 	InitialHeap  uint64  `json:"init_live_heap"`
+
+	// MemoryLimit is the soft memory limit in bytes, or zero if
+	// there is no limit.
+	MemoryLimit uint64 `json:"memory_limit,omitempty"`
+
+	// GOGCOff indicates that GOGC=off, in which case Gamma is
+	// effectively infinite and a memory limit must be set.
+	GOGCOff bool `json:"gogc_off,omitempty"`
 }
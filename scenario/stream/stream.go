@@ -0,0 +1,176 @@
+// Package stream provides composable streams of values, one per GC cycle,
+// for describing how the inputs to a scenario change over time.
+//
+// Streams are stateful: each call returns the value for the next cycle.
+// Combinators consume the streams they're built from, so a stream should
+// only be used in one place.
+package stream
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Stream produces the next value each time it's called.
+type Stream func() float64
+
+// Constant is always c.
+func Constant(c float64) Stream {
+	return func() float64 {
+		return c
+	}
+}
+
+// Unit is amp for the first cycle and zero afterward.
+func Unit(amp float64) Stream {
+	dropped := false
+	return func() float64 {
+		if dropped {
+			return 0
+		}
+		dropped = true
+		return amp
+	}
+}
+
+// Oscillate is a sine wave with amplitude amp and the given phase
+// in radians, repeating every period cycles.
+func Oscillate(amp, phase float64, period int) Stream {
+	var cycle int
+	return func() float64 {
+		p := float64(cycle)/float64(period)*2*math.Pi + phase
+		cycle++
+		if cycle == period {
+			cycle = 0
+		}
+		return math.Sin(p) * amp
+	}
+}
+
+// Ramp rises linearly from zero to height over length cycles,
+// then stays at height.
+func Ramp(height float64, length int) Stream {
+	var cycle int
+	return func() float64 {
+		h := height * float64(cycle) / float64(length)
+		if cycle < length {
+			cycle++
+		}
+		return h
+	}
+}
+
+// Step is zero for the first at cycles, and height from then on.
+func Step(height float64, at int) Stream {
+	var cycle int
+	return func() float64 {
+		if cycle < at {
+			cycle++
+			return 0
+		}
+		return height
+	}
+}
+
+// Random is uniformly distributed noise in [-amp, amp).
+func Random(amp float64) Stream {
+	return func() float64 {
+		return ((rand.Float64() - 0.5) * 2) * amp
+	}
+}
+
+// Delay is zero for the given number of cycles, then f.
+// It panics if cycles is negative.
+func (f Stream) Delay(cycles int) Stream {
+	if cycles < 0 {
+		panic("stream: negative delay")
+	}
+	if cycles == 0 {
+		return f
+	}
+	buf := make([]float64, 0, cycles)
+	next := 0
+	return func() float64 {
+		old := f()
+		if len(buf) < cap(buf) {
+			buf = append(buf, old)
+			return 0
+		}
+		res := buf[next]
+		buf[next] = old
+		next++
+		if next == len(buf) {
+			next = 0
+		}
+		return res
+	}
+}
+
+// VGA multiplies f by gain, like a variable-gain amplifier.
+func (f Stream) VGA(gain Stream) Stream {
+	return func() float64 {
+		return f() * gain()
+	}
+}
+
+// Scale multiplies f by amt.
+func (f Stream) Scale(amt float64) Stream {
+	return f.VGA(Constant(amt))
+}
+
+// Offset adds amt to f.
+func (f Stream) Offset(amt float64) Stream {
+	return func() float64 {
+		old := f()
+		return old + amt
+	}
+}
+
+// Mix adds fs to f.
+func (f Stream) Mix(fs ...Stream) Stream {
+	return func() float64 {
+		sum := f()
+		for _, s := range fs {
+			sum += s()
+		}
+		return sum
+	}
+}
+
+// Quantize rounds f toward zero to a multiple of mult.
+func (f Stream) Quantize(mult float64) Stream {
+	return func() float64 {
+		r := f() / mult
+		if r < 0 {
+			return math.Ceil(r) * mult
+		}
+		return math.Floor(r) * mult
+	}
+}
+
+// Min keeps f at or above min.
+func (f Stream) Min(min float64) Stream {
+	return func() float64 {
+		return math.Max(min, f())
+	}
+}
+
+// Max keeps f at or below max.
+func (f Stream) Max(max float64) Stream {
+	return func() float64 {
+		return math.Min(max, f())
+	}
+}
+
+// Limit keeps f between min and max.
+func (f Stream) Limit(min, max float64) Stream {
+	return func() float64 {
+		v := f()
+		if v < min {
+			v = min
+		} else if v > max {
+			v = max
+		}
+		return v
+	}
+}
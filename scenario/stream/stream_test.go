@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"testing"
+)
+
+func take(s Stream, n int) []float64 {
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = s()
+	}
+	return v
+}
+
+func checkStream(t *testing.T, name string, s Stream, want []float64) {
+	t.Helper()
+	got := take(s, len(want))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
+
+func TestRamp(t *testing.T) {
+	checkStream(t, "Ramp(4, 4)", Ramp(4, 4), []float64{0, 1, 2, 3, 4, 4, 4})
+	checkStream(t, "Ramp(2, 1)", Ramp(2, 1), []float64{0, 2, 2})
+}
+
+func TestStep(t *testing.T) {
+	checkStream(t, "Step(2, 0)", Step(2, 0), []float64{2, 2, 2})
+	checkStream(t, "Step(2, 1)", Step(2, 1), []float64{0, 2, 2})
+	checkStream(t, "Step(2, 3)", Step(2, 3), []float64{0, 0, 0, 2, 2})
+}
+
+func TestDelay(t *testing.T) {
+	checkStream(t, "Ramp(3, 3).Delay(0)", Ramp(3, 3).Delay(0), []float64{0, 1, 2, 3, 3})
+	checkStream(t, "Ramp(3, 3).Delay(1)", Ramp(3, 3).Delay(1), []float64{0, 0, 1, 2, 3, 3})
+	checkStream(t, "Ramp(3, 3).Delay(4)", Ramp(3, 3).Delay(4), []float64{0, 0, 0, 0, 0, 1, 2, 3, 3})
+	checkStream(t, "Unit(1).Delay(2)", Unit(1).Delay(2), []float64{0, 0, 1, 0, 0})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Delay(-1) did not panic")
+		}
+	}()
+	Constant(1).Delay(-1)
+}
+
+func TestQuantize(t *testing.T) {
+	in := []float64{0, 2.5, 4, -2.5, -4, -0.5}
+	i := 0
+	s := Stream(func() float64 {
+		v := in[i]
+		i++
+		return v
+	})
+	// Rounds toward zero.
+	checkStream(t, "Quantize(2)", s.Quantize(2), []float64{0, 2, 4, -2, -4, 0})
+}
@@ -0,0 +1,173 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/mknyszek/pacer-model/estimator"
+)
+
+// SimulatorConfig contains pacer tunables and optional simulator features.
+//
+// Each simulator has its own defaults, which match the pacer it models.
+// Use DefaultConfig to obtain them. Not every simulator uses every tunable.
+type SimulatorConfig struct {
+	// BackgroundUtilization is the GC CPU utilization the pacer targets
+	// for background mark work. It must be in (0, 1).
+	BackgroundUtilization float64 `json:"background_utilization"`
+
+	// AssistUtilization is the GC CPU utilization the pacer expects assists
+	// to use on top of BackgroundUtilization. Used only by go116.
+	AssistUtilization float64 `json:"assist_utilization"`
+
+	// HardGoalRatio is the hard heap goal as a multiple of the heap goal.
+	// Used only by go116.
+	HardGoalRatio float64 `json:"hard_goal_ratio"`
+
+	// MinTriggerRatio and MaxTriggerRatio bound the trigger to between these
+	// fractions of the way from the live heap to the heap goal. go117 does
+	// not use MaxTriggerRatio.
+	MinTriggerRatio float64 `json:"min_trigger_ratio"`
+	MaxTriggerRatio float64 `json:"max_trigger_ratio"`
+
+	// RMargin keeps R between RMargin and Gamma-RMargin. Used only by go117.
+	RMargin float64 `json:"r_margin"`
+
+	// HeapMinimum is the minimum heap goal in bytes. For go118 and go119,
+	// this is the heap minimum at GOGC=100, and it scales linearly with GOGC.
+	HeapMinimum uint64 `json:"heap_minimum"`
+
+	// CPULimiter enables a model of the Go 1.20 GC CPU limiter.
+	// Not supported by go116, for which NewSimulator returns an error.
+	CPULimiter bool `json:"cpu_limiter"`
+
+	// CPULimiterCapacity is the capacity of the GC CPU limiter's bucket.
+	// Allocation and scan rates are treated as bytes per nanosecond of total
+	// CPU time, so the default of 1e9 is 1 CPU-second per P, like the runtime.
+	CPULimiterCapacity float64 `json:"cpu_limiter_capacity"`
+
+	// Trace enables recording pacer internals for each GC cycle
+	// in Result.Trace.
+	Trace bool `json:"trace"`
+
+	// Kalman, if not nil, configures a Kalman filter that estimates the
+	// signal the pacer measures each cycle before it's passed to the
+	// controller. For go116 this is the actual heap growth ratio, and
+	// for go117 it's R. go118 and go119 have no controller, and reject it.
+	Kalman *estimator.KalmanConfig `json:"kalman,omitempty"`
+
+	// Smoother, if not nil, configures a smoother for the same signal
+	// as Kalman. At most one of them may be set.
+	Smoother *estimator.SmootherConfig `json:"smoother,omitempty"`
+
+	// Feedforward is the gain of a feedforward path that adjusts R at the
+	// start of each cycle by the change in the ideal R predicted from the
+	// change in the ratio of allocation rate to scan rate since the last
+	// cycle. Zero disables it. Used only by go117 and go117-discrete.
+	Feedforward float64 `json:"feedforward,omitempty"`
+
+	// MeasurementDelay is the number of cycles by which the measurements
+	// passed to the controller lag behind the pacer, like in the runtime,
+	// where stats are only flushed after sweeping. The controller doesn't
+	// run until its first measurement arrives. Used only by go117 and
+	// go117-discrete.
+	MeasurementDelay int `json:"measurement_delay,omitempty"`
+}
+
+// validate checks c for the named simulator.
+func (c *SimulatorConfig) validate(name string) error {
+	if c.CPULimiter && name == "go116" {
+		return fmt.Errorf("pacer type %q doesn't support the CPU limiter", name)
+	}
+	if c.BackgroundUtilization <= 0 || c.BackgroundUtilization >= 1 {
+		return fmt.Errorf("background utilization must be in (0, 1), got %v", c.BackgroundUtilization)
+	}
+	if c.Kalman != nil && c.Smoother != nil {
+		return fmt.Errorf("at most one of a Kalman filter and a smoother may be configured")
+	}
+	if c.MeasurementDelay < 0 {
+		return fmt.Errorf("measurement delay must not be negative")
+	}
+	if (c.Kalman != nil || c.Smoother != nil) && (name == "go118" || name == "go119") {
+		return fmt.Errorf("pacer type %q doesn't support an estimator", name)
+	}
+	if c.Feedforward != 0 && !isGo117(name) {
+		return fmt.Errorf("pacer type %q doesn't support feedforward", name)
+	}
+	if c.MeasurementDelay != 0 && !isGo117(name) {
+		return fmt.Errorf("pacer type %q doesn't support a measurement delay", name)
+	}
+	if c.Smoother != nil {
+		if _, err := estimator.NewSmoother(c.Smoother); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isGo117 reports whether the named simulator is go117 or a variant of it.
+func isGo117(name string) bool {
+	return name == "go117" || name == "go117-discrete"
+}
+
+// newEstimator returns the configured estimator, or nil if there is none.
+// c must have been validated.
+func (c *SimulatorConfig) newEstimator() estimator.Estimator {
+	switch {
+	case c.Kalman != nil:
+		return estimator.NewKalman(c.Kalman)
+	case c.Smoother != nil:
+		e, _ := estimator.NewSmoother(c.Smoother)
+		return e
+	}
+	return nil
+}
+
+func (c *SimulatorConfig) newCPULimiter() *cpuLimiter {
+	if !c.CPULimiter {
+		return nil
+	}
+	return &cpuLimiter{capacity: c.CPULimiterCapacity}
+}
+
+var (
+	go116Config = SimulatorConfig{
+		BackgroundUtilization: 0.25,
+		AssistUtilization:     0.05,
+		HardGoalRatio:         1.1,
+		MinTriggerRatio:       0.6,
+		MaxTriggerRatio:       0.95,
+		HeapMinimum:           4 << 20,
+		CPULimiterCapacity:    1e9,
+	}
+	go117Config = SimulatorConfig{
+		BackgroundUtilization: 0.25,
+		MinTriggerRatio:       0.6,
+		RMargin:               0.05,
+		HeapMinimum:           2 << 20,
+		CPULimiterCapacity:    1e9,
+	}
+	go118Config = SimulatorConfig{
+		BackgroundUtilization: 0.25,
+		MinTriggerRatio:       0.7,
+		MaxTriggerRatio:       0.95,
+		HeapMinimum:           4 << 20,
+		CPULimiterCapacity:    1e9,
+	}
+)
+
+var defaultConfigs = map[string]*SimulatorConfig{
+	"go116":          &go116Config,
+	"go117":          &go117Config,
+	"go117-discrete": &go117Config,
+	"go118":          &go118Config,
+	"go119":          &go118Config,
+}
+
+// DefaultConfig returns the default configuration for the named simulator.
+func DefaultConfig(name string) (SimulatorConfig, error) {
+	cfg, ok := defaultConfigs[name]
+	if !ok {
+		return SimulatorConfig{}, fmt.Errorf("unknown pacer type %q", name)
+	}
+	return *cfg, nil
+}
@@ -0,0 +1,75 @@
+package simulation
+
+import (
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+// cpuLimiterMaxUtilization is the GC CPU utilization the limiter
+// caps the GC at while it's engaged.
+const cpuLimiterMaxUtilization = 0.5
+
+// cpuLimiter models the Go 1.20 GC CPU limiter, a leaky bucket of GC CPU
+// time. The bucket fills while the GC uses more than half the CPU, and drains
+// otherwise. Once the bucket is full, the limiter engages and caps GC CPU
+// utilization at 50%, letting the heap overshoot the goal instead.
+type cpuLimiter struct {
+	capacity float64
+	fill     float64
+}
+
+// mark simulates the limiter over a GC cycle, given the time the mutator ran
+// alone before the GC was triggered and the total scan work for the mark phase,
+// which the pacer wants to do at a GC CPU utilization of u.
+//
+// Returns the effective alloc/scan ratio over the mark phase, the actual GC
+// CPU utilization (time-weighted), and whether the limiter engaged.
+func (l *cpuLimiter) mark(gc *scenario.Cycle, mutatorTime float64, scanWork uint64, u float64) (ratio, actualU float64, engaged bool) {
+	l.drain(mutatorTime)
+
+	ratio = (gc.AllocRate * (1 - u)) / (gc.ScanRate * u)
+	markTime := float64(scanWork) / (gc.ScanRate * u)
+	if u <= cpuLimiterMaxUtilization {
+		l.drain((1 - 2*u) * markTime)
+		return ratio, u, false
+	}
+	fillTime := (l.capacity - l.fill) / (2*u - 1)
+	if fillTime >= markTime {
+		l.fill += (2*u - 1) * markTime
+		return ratio, u, false
+	}
+
+	// The bucket filled up partway through the mark phase. The rest
+	// of the scan work happens with GC CPU utilization capped.
+	const ul = cpuLimiterMaxUtilization
+	l.fill = l.capacity
+	scanWorkDone := gc.ScanRate * u * fillTime
+	scanWorkLeft := float64(scanWork) - scanWorkDone
+	limitedTime := scanWorkLeft / (gc.ScanRate * ul)
+	limitedRatio := (gc.AllocRate * (1 - ul)) / (gc.ScanRate * ul)
+	ratio = (ratio*scanWorkDone + limitedRatio*scanWorkLeft) / float64(scanWork)
+	actualU = (u*fillTime + ul*limitedTime) / (fillTime + limitedTime)
+	return ratio, actualU, true
+}
+
+func (l *cpuLimiter) drain(t float64) {
+	l.fill -= t
+	if l.fill < 0 {
+		l.fill = 0
+	}
+}
+
+// step advances the limiter by a time slice of length dt in which the GC
+// wants to use a CPU utilization of u. Returns the utilization the GC is
+// actually allowed to use, and whether the limiter engaged.
+func (l *cpuLimiter) step(u, dt float64) (float64, bool) {
+	if u > cpuLimiterMaxUtilization && l.fill >= l.capacity {
+		return cpuLimiterMaxUtilization, true
+	}
+	l.fill += (2*u - 1) * dt
+	if l.fill < 0 {
+		l.fill = 0
+	} else if l.fill > l.capacity {
+		l.fill = l.capacity
+	}
+	return u, false
+}
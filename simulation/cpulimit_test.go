@@ -0,0 +1,68 @@
+package simulation
+
+import "testing"
+
+func runLimiter(t *testing.T, name, scn string, limiter bool) []Result {
+	t.Helper()
+	e := loadScenario(t, scn)
+	cfg, err := DefaultConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.CPULimiter = limiter
+	cfg.CPULimiterCapacity = 1e5
+	s, err := NewSimulator(name, &e, nil, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r []Result
+	for i := range e.Cycles {
+		r = append(r, s.Step(&e.Cycles[i]))
+	}
+	return r
+}
+
+func TestCPULimiter(t *testing.T) {
+	for _, tc := range []struct{ name, scn string }{
+		{"go117", "heavy-step-alloc"},
+		{"go118", "heavy-jitter-alloc"},
+	} {
+		t.Run(tc.name+"/"+tc.scn, func(t *testing.T) {
+			limited := runLimiter(t, tc.name, tc.scn, true)
+			unlimited := runLimiter(t, tc.name, tc.scn, false)
+			first := -1
+			for i, r := range limited {
+				if r.CPULimited {
+					first = i
+					break
+				}
+			}
+			if first < 0 {
+				t.Fatal("the limiter never engaged")
+			}
+			released := false
+			for _, r := range limited[first:] {
+				if !r.CPULimited {
+					released = true
+					break
+				}
+			}
+			if !released {
+				t.Errorf("the limiter engaged in cycle %d and never released", first)
+			}
+
+			// Up to the first limited cycle, both runs are the same,
+			// so the limiter's effect on that cycle can be compared.
+			l, u := limited[first], unlimited[first]
+			if u.CPULimited {
+				t.Errorf("cycle %d is CPU limited without the limiter", first)
+			}
+			if l.ActualGCUtilization >= u.ActualGCUtilization {
+				t.Errorf("cycle %d: GC CPU utilization %f with the limiter, want less than %f", first, l.ActualGCUtilization, u.ActualGCUtilization)
+			}
+			if l.PeakBytes <= u.PeakBytes {
+				t.Errorf("cycle %d: peak heap %d with the limiter, want more than %d", first, l.PeakBytes, u.PeakBytes)
+			}
+		})
+	}
+}
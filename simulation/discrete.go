@@ -0,0 +1,111 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+// go117Discrete is the go117 pacer, but instead of relying on the during-GC
+// pacer to work perfectly, it steps through each mark phase in small time
+// slices.
+type go117Discrete struct {
+	go117
+
+	// State
+	allocRateLast float64
+	scanRateLast  float64
+}
+
+// discreteSlices is the number of time slices it takes to do all the
+// scan work in a mark phase without assists.
+const discreteSlices = 1000
+
+func (s *go117Discrete) Step(gc *scenario.Cycle) Result {
+	s.trace = newTrace(&s.cfg)
+	s.ctrl.begin()
+
+	// Simulate up to when GC starts.
+	heapGoal, triggerPoint := s.trigger(gc)
+	if s.gc == 0 {
+		s.allocRateLast = gc.AllocRate
+		s.scanRateLast = gc.ScanRate
+	}
+	if s.cpuLimiter != nil {
+		s.cpuLimiter.drain(float64(triggerPoint-s.liveBytesLast) / gc.AllocRate)
+	}
+
+	// Simulate during-GC pacing.
+	//
+	// The allocation and scan rates move linearly from last cycle's
+	// rates to this cycle's over the expected length of the mark phase.
+	// Every slice, the assist ratio is revised based on the scan work
+	// done and the heap allocated so far, like the runtime's revise.
+	u := s.cfg.BackgroundUtilization
+	expScanWork := s.liveScannableLast + gc.StackBytes + s.GlobalsBytes
+	totalScanWork := s.totalScanWork(gc)
+	hardHeapGoal := s.Gamma * float64(heapGoal)
+
+	expMarkTime := float64(expScanWork) / (gc.ScanRate * u)
+	dt := math.Max(float64(expScanWork), float64(totalScanWork)) / (gc.ScanRate * u) / discreteSlices
+
+	heapLive := float64(triggerPoint)
+	var scanWorkDone, markTime, gcTime, maxAssistWorkPerByte float64
+	var slices int
+	var cpuLimited bool
+	for ; scanWorkDone < float64(totalScanWork); slices++ {
+		progress := math.Min(markTime/expMarkTime, 1)
+		allocRate := s.allocRateLast + (gc.AllocRate-s.allocRateLast)*progress
+		scanRate := s.scanRateLast + (gc.ScanRate-s.scanRateLast)*progress
+
+		// If we've already done more scan work than expected, assume
+		// the worst case: everything allocated since the last GC is live.
+		// Pace against the hard goal instead.
+		scanWorkExpected, goal := float64(expScanWork), float64(heapGoal)
+		if scanWorkDone > scanWorkExpected {
+			scanWorkExpected += (heapLive - float64(s.liveBytesLast)) * gc.ScannableFrac
+			goal = hardHeapGoal
+		}
+		scanWorkRemaining := math.Max(scanWorkExpected-scanWorkDone, 1)
+		heapRemaining := math.Max(goal-heapLive, 1)
+		assistWorkPerByte := scanWorkRemaining / heapRemaining
+		maxAssistWorkPerByte = math.Max(maxAssistWorkPerByte, assistWorkPerByte)
+
+		// Mutators split their CPU time between allocating (f) and
+		// assisting (1-f) such that scan work keeps pace with allocation.
+		//
+		// scanRate * (u + (1-u) * (1-f)) = assistWorkPerByte * allocRate * (1-u) * f
+		// => f = scanRate / ((1-u) * (scanRate + assistWorkPerByte * allocRate))
+		f := scanRate / ((1 - u) * (scanRate + assistWorkPerByte*allocRate))
+		if f > 1 {
+			f = 1
+		}
+		sliceU := u + (1-u)*(1-f)
+		if s.cpuLimiter != nil {
+			var limited bool
+			sliceU, limited = s.cpuLimiter.step(sliceU, dt)
+			cpuLimited = cpuLimited || limited
+		}
+
+		sliceTime := dt
+		scanWork := scanRate * sliceU * dt
+		if left := float64(totalScanWork) - scanWorkDone; scanWork > left {
+			sliceTime = dt * left / scanWork
+			scanWork = left
+		}
+		heapLive += allocRate * (1 - sliceU) * sliceTime
+		scanWorkDone += scanWork
+		gcTime += sliceU * sliceTime
+		markTime += sliceTime
+	}
+	peakHeap := uint64(heapLive)
+	actualU := gcTime / markTime
+	s.trace.record("mark_time", markTime)
+	s.trace.record("slices", float64(slices))
+	s.trace.record("max_assist_work_per_byte", maxAssistWorkPerByte)
+	s.allocRateLast = gc.AllocRate
+	s.scanRateLast = gc.ScanRate
+
+	// Simulate GC feedback loop.
+	return s.feedback(gc, heapGoal, triggerPoint, peakHeap, totalScanWork, actualU, cpuLimited)
+}
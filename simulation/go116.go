@@ -1,11 +1,18 @@
 package simulation
 
 import (
+	"github.com/mknyszek/pacer-model/estimator"
 	"github.com/mknyszek/pacer-model/scenario"
 )
 
 type go116 struct {
 	scenario.Globals
+	cfg  SimulatorConfig
+	ctrl loop
+
+	// estimator filters measurements of the heap growth ratio,
+	// or is nil if disabled.
+	estimator estimator.Estimator
 
 	// State
 	gc                      int
@@ -17,9 +24,29 @@ type go116 struct {
 	triggerRatio            float64
 }
 
-const go116HeapMinimum = 4 << 20
+// Cases for how go116 computes the peak heap, recorded in traces
+// as "peak_case".
+const (
+	// As much scan work as expected, and the heap stayed under the goal.
+	go116PeakWithinGoal = iota + 1
+
+	// As much scan work as expected, but the heap exceeded the goal,
+	// so the GC paced for the worst case up to the hard goal.
+	go116PeakHardGoal
+
+	// More scan work than expected, and the heap exceeded the goal
+	// before the expected scan work was done.
+	go116PeakExtraWorkHardGoal
+
+	// More scan work than expected, so the GC paced the rest of the work
+	// for the worst case.
+	go116PeakExtraWorkWorstCase
+)
 
 func (s *go116) Step(gc *scenario.Cycle) Result {
+	tr := newTrace(&s.cfg)
+	s.ctrl.begin()
+
 	// Simulate up to when GC starts.
 	//
 	// 1. Figure out the goal.
@@ -30,8 +57,8 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 	if target := gc.HeapTargetBytes; target > 0 && heapGoal < uint64(target) {
 		heapGoal = uint64(target)
 	}
-	if heapGoal < go116HeapMinimum {
-		heapGoal = go116HeapMinimum
+	if heapGoal < s.cfg.HeapMinimum {
+		heapGoal = s.cfg.HeapMinimum
 	}
 	if s.gc == 0 {
 		s.triggerRatioRaw = 7.0 / 8.0
@@ -47,6 +74,9 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 		maxScanWork = dummyLiveLast + uint64(float64(triggerPoint-dummyLiveLast)*gc.ScannableFrac)
 		expScanWork = uint64(float64(maxScanWork) / s.Gamma)
 	}
+	tr.record("max_scan_work", float64(maxScanWork))
+	tr.record("exp_scan_work", float64(expScanWork))
+	tr.record("dummy_live_last", float64(dummyLiveLast))
 
 	// Simulate during-GC pacing.
 	//
@@ -61,18 +91,19 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 	} else {
 		totalScanWork = uint64(float64(s.liveScannableLast)*gc.GrowthRate) + gc.StackBytes + s.GlobalsBytes
 	}
+	tr.record("total_scan_work", float64(totalScanWork))
 
 	// Rely on the during-GC pacer to work perfectly.
 	// Target utilization is 25% + 5% for assists.
 	//
 	// extra = (allocRate * (1-u)) / (scanRate * u) * totalScanWork
 	//
-	// Set a hard goal of 1.1 * heapGoal.
+	// Set a hard goal of 1.1 * heapGoal (by default).
 	// If there's more scan work than expected, or if we exceed the heap
 	// goal at any point, pace for the worst case.
-	const uTargetDedicated = 0.25
-	const uTargetAssist = 0.05
-	const uTarget = uTargetDedicated + uTargetAssist
+	uTargetDedicated := s.cfg.BackgroundUtilization
+	uTargetAssist := s.cfg.AssistUtilization
+	uTarget := uTargetDedicated + uTargetAssist
 
 	// This gets complicated. allocated memory counts both toward work done
 	// AND how much runway we have.
@@ -90,7 +121,11 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 	}
 	r := (gc.AllocRate * (1 - uExp)) / (gc.ScanRate * uExp)
 	uActual := uExp
-	hardHeapGoal := uint64(1.1 * float64(heapGoal))
+	hardHeapGoal := uint64(s.cfg.HardGoalRatio * float64(heapGoal))
+	tr.record("assist_ratio", assistRatioRelaxed)
+	tr.record("u_exp", uExp)
+	tr.record("hard_goal", float64(hardHeapGoal))
+	tr.record("peak_case", go116PeakWithinGoal)
 	var peakHeap uint64
 	if expScanWork >= totalScanWork {
 		peakExtra := uint64(r * float64(totalScanWork))
@@ -107,6 +142,8 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 			// uActual * totalScanWork = uExp * expScanWork + uWorst * scanWorkLeftAtGoal
 			uActual = (uExp*float64(scanWorkDone) + uWorst*float64(scanWorkLeft)) / float64(totalScanWork)
 			peakHeap = hardHeapGoal
+			tr.record("u_worst", uWorst)
+			tr.record("peak_case", go116PeakHardGoal)
 		}
 	} else {
 		peakExtra := uint64(r * float64(expScanWork))
@@ -122,6 +159,8 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 			scanWorkLeft := totalScanWork - scanWorkDone
 			uActual = (uExp*float64(scanWorkDone) + uWorst*float64(scanWorkLeft)) / float64(totalScanWork)
 			peakHeap = hardHeapGoal
+			tr.record("u_worst", uWorst)
+			tr.record("peak_case", go116PeakExtraWorkHardGoal)
 		} else {
 			scanWorkDone := expScanWork
 			estScanWorkLeft := maxScanWork - scanWorkDone
@@ -134,6 +173,8 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 			extra := uint64((gc.AllocRate * (1 - uWorst)) / (gc.ScanRate * uWorst) * float64(scanWorkLeft))
 			uActual = (uExp*float64(scanWorkDone) + uWorst*float64(scanWorkLeft)) / float64(totalScanWork)
 			peakHeap += extra
+			tr.record("u_worst", uWorst)
+			tr.record("peak_case", go116PeakExtraWorkWorstCase)
 		}
 	}
 
@@ -142,7 +183,7 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 	// 1. Figure out how much survived this GC.
 	// 2. Use that and other values computed earlier to determine
 	//    what r was and our setpoint for r.
-	// 3. Run a step of the P controller.
+	// 3. Run a step of the controller.
 	// 4. Feed how much data survived to the next cycle.
 
 	heapAllocBlack := peakHeap - triggerPoint
@@ -166,8 +207,8 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 		nextHeapGoal = uint64(target)
 		nextGammaGoal = false
 	}
-	if nextHeapGoal < go116HeapMinimum {
-		nextHeapGoal = go116HeapMinimum
+	if nextHeapGoal < s.cfg.HeapMinimum {
+		nextHeapGoal = s.cfg.HeapMinimum
 		nextGammaGoal = false
 	}
 
@@ -181,16 +222,32 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 	} else {
 		actualGrowthRatio = float64(peakHeap)/float64(s.liveBytesLast) - 1
 	}
-	delta := 0.5 * (goalGrowthRatio - s.triggerRatioRaw - uActual/uTarget*(actualGrowthRatio-s.triggerRatioRaw))
-	s.triggerRatioRaw += delta
+	growthEstimate := actualGrowthRatio
+	if s.estimator != nil {
+		growthEstimate = s.estimator.Update(actualGrowthRatio)
+		if v, ok := s.estimator.(estimator.VarianceEstimator); ok {
+			tr.record("growth_variance", v.Variance())
+		}
+		tr.record("growth_estimate", growthEstimate)
+	}
+	// The controller drives how far the heap grew past the trigger, scaled
+	// by how much harder the GC had to work than expected, toward how far
+	// the heap was supposed to grow past the trigger.
+	measuredGrowth := uActual / uTarget * (growthEstimate - s.triggerRatioRaw)
+	tr.record("goal_growth_ratio", goalGrowthRatio)
+	tr.record("actual_growth_ratio", actualGrowthRatio)
+	tr.record("measured_growth", measuredGrowth)
+	s.triggerRatioRaw += s.ctrl.next(measuredGrowth, goalGrowthRatio-s.triggerRatioRaw)
 	s.triggerRatio = s.triggerRatioRaw
-	if s.triggerRatio < 0.6*(s.Gamma-1) {
-		s.triggerRatio = 0.6 * (s.Gamma - 1)
-	} else if nextGammaGoal && s.triggerRatio > 0.95*(s.Gamma-1) {
-		s.triggerRatio = 0.95 * (s.Gamma - 1)
-	} else if nextGoalGR := float64(nextHeapGoal)/float64(heapSurvived) - 1; !nextGammaGoal && s.triggerRatio > 0.95*nextGoalGR {
-		s.triggerRatio = 0.95 * nextGoalGR
+	if s.triggerRatio < s.cfg.MinTriggerRatio*(s.Gamma-1) {
+		s.triggerRatio = s.cfg.MinTriggerRatio * (s.Gamma - 1)
+	} else if nextGammaGoal && s.triggerRatio > s.cfg.MaxTriggerRatio*(s.Gamma-1) {
+		s.triggerRatio = s.cfg.MaxTriggerRatio * (s.Gamma - 1)
+	} else if nextGoalGR := float64(nextHeapGoal)/float64(heapSurvived) - 1; !nextGammaGoal && s.triggerRatio > s.cfg.MaxTriggerRatio*nextGoalGR {
+		s.triggerRatio = s.cfg.MaxTriggerRatio * nextGoalGR
 	}
+	tr.record("trigger_ratio_raw", s.triggerRatioRaw)
+	tr.record("trigger_ratio", s.triggerRatio)
 	s.liveBytesLast = heapSurvived
 	s.liveScannableLast = heapScannableSurvived
 	s.allocBlackLast = heapAllocBlack
@@ -209,5 +266,7 @@ func (s *go116) Step(gc *scenario.Cycle) Result {
 		TargetGCUtilization: uTarget,
 		TriggerPoint:        triggerPoint,
 		PeakBytes:           peakHeap,
+		Trace:               tr,
+		Controller:          s.ctrl.signals,
 	}
 }
@@ -2,12 +2,18 @@ package simulation
 
 import (
 	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/estimator"
 	"github.com/mknyszek/pacer-model/scenario"
 )
 
 type go117 struct {
 	scenario.Globals
-	ctrl controller.Controller
+	cfg        SimulatorConfig
+	ctrl       loop
+	cpuLimiter *cpuLimiter
+
+	// estimator filters measurements of R, or is nil if disabled.
+	estimator estimator.Estimator
 
 	// State
 	gc                      int
@@ -16,23 +22,72 @@ type go117 struct {
 	allocBlackLast          uint64
 	allocBlackScannableLast uint64
 	rValue                  float64
-}
+	allocScanLast           float64
+	trace                   Trace
 
-const go117HeapMinimum = 2 << 20
+	// delayed holds measurements not yet passed to the controller,
+	// when it's configured to receive them late.
+	delayed []controller.Observation
+}
 
 func (s *go117) Step(gc *scenario.Cycle) Result {
+	s.trace = newTrace(&s.cfg)
+	s.ctrl.begin()
+
 	// Simulate up to when GC starts.
+	heapGoal, triggerPoint := s.trigger(gc)
+
+	// Simulate during-GC pacing.
+	assistRatio := (float64(heapGoal) - float64(triggerPoint)) / float64(s.liveScannableLast+gc.StackBytes+s.GlobalsBytes)
+	totalScanWork := s.totalScanWork(gc)
+	s.trace.record("assist_ratio", assistRatio)
+
+	// Rely on the during-GC pacer to work perfectly.
 	//
-	// 1. Figure out the goal.
-	// 2. Figure out the trigger.
-	// 3. Figure out the worst-case scan work.
+	// Set a hard goal of gamma * heapGoal.
+	u := s.cfg.BackgroundUtilization
+	actualRatio := (gc.AllocRate * (1 - u)) / (gc.ScanRate * u)
+	actualU := u
+	if actualRatio > assistRatio {
+		actualRatio = assistRatio
+		// ratio = (allocRate * (1-u)) / (scanRate * u)
+		// => ratio = (allocRate - allocRate * u) / (scanRate * u)
+		// => ratio * scanRate * u = allocRate - allocRate * u
+		// => ratio * scanRate * u + allocRate * u = allocRate
+		// => u * (ratio * scanRate + allocRate) = allocRate
+		// => u = allocRate / (ratio * scanRate + allocRate)
+		// if x = allocRate / (ratio * scanRate)
+		// => u = x / (1 + x)
+		x := gc.AllocRate / (actualRatio * gc.ScanRate)
+		actualU = x / (1 + x)
+	}
+	var cpuLimited bool
+	if s.cpuLimiter != nil {
+		mutatorTime := float64(triggerPoint-s.liveBytesLast) / gc.AllocRate
+		actualRatio, actualU, cpuLimited = s.cpuLimiter.mark(gc, mutatorTime, totalScanWork, actualU)
+	}
+	peakExtra := uint64(actualRatio * float64(totalScanWork))
+	peakHeap := triggerPoint + peakExtra
+	s.trace.record("actual_ratio", actualRatio)
 
-	heapGoal := uint64(float64(s.liveBytesLast+gc.StackBytes+s.GlobalsBytes) * s.Gamma)
+	// Simulate GC feedback loop.
+	return s.feedback(gc, heapGoal, triggerPoint, peakHeap, totalScanWork, actualU, cpuLimited)
+}
+
+// trigger computes the heap goal and trigger point for the GC cycle.
+func (s *go117) trigger(gc *scenario.Cycle) (heapGoal, triggerPoint uint64) {
+	// 1. Adjust R for changes in the allocation rate, if enabled.
+	// 2. Figure out the goal.
+	// 3. Figure out the trigger.
+
+	s.feedforward(gc)
+
+	heapGoal = uint64(float64(s.liveBytesLast+gc.StackBytes+s.GlobalsBytes) * s.Gamma)
 	if target := gc.HeapTargetBytes; target > 0 && heapGoal < uint64(target) {
 		heapGoal = uint64(target)
 	}
-	if heapGoal < go117HeapMinimum {
-		heapGoal = go117HeapMinimum
+	if heapGoal < s.cfg.HeapMinimum {
+		heapGoal = s.cfg.HeapMinimum
 	}
 
 	// expectedScan = liveScannableLast + stackBytes + globalsBytes
@@ -42,7 +97,7 @@ func (s *go117) Step(gc *scenario.Cycle) Result {
 	//
 	// => extraTilTrigger = heapGoal - r * (liveScannableLast + stackBytes + globalsBytes) - liveBytesLast
 	// => triggerPoint = liveBytesLast + extraTilTrigger
-	var extraTilTrigger, triggerPoint uint64
+	var extraTilTrigger uint64
 	if s.gc == 0 {
 		//extraTilTrigger = 7 * heapGoal / 8
 		triggerPoint = 7 * heapGoal / 8
@@ -52,52 +107,60 @@ func (s *go117) Step(gc *scenario.Cycle) Result {
 			extraTilTrigger = heapGoal - backwards
 		}
 		triggerPoint = s.liveBytesLast + extraTilTrigger
-		if minTrigger := uint64(float64(heapGoal-s.liveBytesLast)*0.6) + s.liveBytesLast; triggerPoint < minTrigger {
+		if minTrigger := uint64(float64(heapGoal-s.liveBytesLast)*s.cfg.MinTriggerRatio) + s.liveBytesLast; triggerPoint < minTrigger {
 			triggerPoint = minTrigger
 			extraTilTrigger = minTrigger - s.liveBytesLast
 		}
 	}
 
-	// Simulate during-GC pacing.
-	assistRatio := (float64(heapGoal) - float64(triggerPoint)) / float64(s.liveScannableLast+gc.StackBytes+s.GlobalsBytes)
+	s.trace.record("exp_scan_work", float64(s.liveScannableLast+gc.StackBytes+s.GlobalsBytes))
+	return heapGoal, triggerPoint
+}
 
-	var totalScanWork uint64
-	if s.gc == 0 {
-		totalScanWork = s.InitialHeap + gc.StackBytes + s.GlobalsBytes
-	} else {
-		totalScanWork = uint64(float64(s.liveScannableLast)*gc.GrowthRate) + gc.StackBytes + s.GlobalsBytes
+// feedforward adjusts R for the change in the ratio of allocation rate
+// to scan rate since the last cycle, before the trigger is computed.
+func (s *go117) feedforward(gc *scenario.Cycle) {
+	ratio := gc.AllocRate / gc.ScanRate
+	if s.gc != 0 && s.cfg.Feedforward != 0 {
+		// The ideal R is ratio * (1 - u) / u.
+		u := s.cfg.BackgroundUtilization
+		adjust := s.cfg.Feedforward * (ratio - s.allocScanLast) * (1 - u) / u
+		s.rValue += adjust
+		s.clampR()
+		s.trace.record("feedforward", adjust)
 	}
+	s.allocScanLast = ratio
+}
 
-	// Rely on the during-GC pacer to work perfectly.
-	//
-	// Set a hard goal of gamma * heapGoal.
-	const u = 0.25
-	actualRatio := (gc.AllocRate * (1 - u)) / (gc.ScanRate * u)
-	actualU := float64(u)
-	if actualRatio > assistRatio {
-		actualRatio = assistRatio
-		// ratio = (allocRate * (1-u)) / (scanRate * u)
-		// => ratio = (allocRate - allocRate * u) / (scanRate * u)
-		// => ratio * scanRate * u = allocRate - allocRate * u
-		// => ratio * scanRate * u + allocRate * u = allocRate
-		// => u * (ratio * scanRate + allocRate) = allocRate
-		// => u = allocRate / (ratio * scanRate + allocRate)
-		// if x = allocRate / (ratio * scanRate)
-		// => u = x / (1 + x)
-		x := gc.AllocRate / (actualRatio * gc.ScanRate)
-		actualU = x / (1 + x)
+// clampR keeps R between RMargin and Gamma-RMargin.
+func (s *go117) clampR() {
+	if s.rValue < s.cfg.RMargin {
+		s.rValue = s.cfg.RMargin
+	} else if s.rValue > s.Gamma-s.cfg.RMargin {
+		s.rValue = s.Gamma - s.cfg.RMargin
 	}
-	peakExtra := uint64(actualRatio * float64(totalScanWork))
-	peakHeap := triggerPoint + peakExtra
+}
 
-	// Simulate GC feedback loop.
-	//
+// totalScanWork returns the actual scan work for the GC cycle.
+func (s *go117) totalScanWork(gc *scenario.Cycle) uint64 {
+	if s.gc == 0 {
+		return s.InitialHeap + gc.StackBytes + s.GlobalsBytes
+	}
+	return uint64(float64(s.liveScannableLast)*gc.GrowthRate) + gc.StackBytes + s.GlobalsBytes
+}
+
+// feedback simulates the GC feedback loop at the end of a GC cycle
+// and returns the cycle's result.
+func (s *go117) feedback(gc *scenario.Cycle, heapGoal, triggerPoint, peakHeap, totalScanWork uint64, actualU float64, cpuLimited bool) Result {
 	// 1. Figure out how much survived this GC.
 	// 2. Use that and other values computed earlier to determine
 	//    what r was and our setpoint for r.
-	// 3. Run a step of the PI controller.
+	// 3. Estimate R from the measurement, if enabled, and run a step
+	//    of the PI controller.
 	// 4. Feed how much data survived to the next cycle.
 
+	u := s.cfg.BackgroundUtilization
+
 	heapAllocBlack := peakHeap - triggerPoint
 	heapAllocBlackScannable := uint64(float64(heapAllocBlack) * gc.ScannableFrac)
 
@@ -111,14 +174,47 @@ func (s *go117) Step(gc *scenario.Cycle) Result {
 	}
 
 	rMeasured := float64(peakHeap-triggerPoint) / float64(totalScanWork) * ((1 - u) / (1 - actualU)) / (u / actualU)
+	s.trace.record("total_scan_work", float64(totalScanWork))
+	s.trace.record("r_measured", rMeasured)
+
+	rEstimate := rMeasured
+	var rVariance float64
+	if s.estimator != nil {
+		rEstimate = s.estimator.Update(rMeasured)
+		if v, ok := s.estimator.(estimator.VarianceEstimator); ok {
+			rVariance = v.Variance()
+		}
+		s.trace.record("r_estimate", rEstimate)
+		s.trace.record("r_variance", rVariance)
+	}
+
+	liveBytes := s.liveBytesLast
+	if s.gc == 0 {
+		liveBytes = s.InitialHeap
+	}
 
+	// Pass measurements to the controller after the configured delay.
 	thisR := s.rValue
-	s.rValue += s.ctrl.Next(s.rValue, rMeasured)
-	if s.rValue < 0.05 {
-		s.rValue = 0.05
-	} else if s.rValue > s.Gamma-0.05 {
-		s.rValue = s.Gamma - 0.05
+	s.delayed = append(s.delayed, controller.Observation{
+		Input:             s.rValue,
+		Setpoint:          rEstimate,
+		Gamma:             s.Gamma,
+		HeapGoal:          float64(heapGoal),
+		LiveHeap:          float64(liveBytes),
+		HeapTargetActive:  gc.HeapTargetBytes > 0 && heapGoal == uint64(gc.HeapTargetBytes),
+		ExpScanWork:       float64(s.liveScannableLast + gc.StackBytes + s.GlobalsBytes),
+		ScanWork:          float64(totalScanWork),
+		TargetUtilization: u,
+		MeasurementDelay:  s.cfg.MeasurementDelay,
+		InputMin:          s.cfg.RMargin,
+		InputMax:          s.Gamma - s.cfg.RMargin,
+	})
+	if len(s.delayed) > s.cfg.MeasurementDelay {
+		obs := s.delayed[0]
+		s.delayed = s.delayed[1:]
+		s.rValue += s.ctrl.observe(&obs)
 	}
+	s.clampR()
 	s.liveBytesLast = heapSurvived
 	s.liveScannableLast = heapScannableSurvived
 	s.allocBlackLast = heapAllocBlack
@@ -137,5 +233,9 @@ func (s *go117) Step(gc *scenario.Cycle) Result {
 		TargetGCUtilization: u,
 		TriggerPoint:        triggerPoint,
 		PeakBytes:           peakHeap,
+		CPULimited:          cpuLimited,
+		RVariance:           rVariance,
+		Trace:               s.trace,
+		Controller:          s.ctrl.signals,
 	}
 }
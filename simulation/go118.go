@@ -0,0 +1,210 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+type go118 struct {
+	scenario.Globals
+
+	// memoryLimit enables the Go 1.19 memory limit, which
+	// also makes it possible to turn off GOGC.
+	memoryLimit bool
+
+	cfg SimulatorConfig
+
+	// cpuLimiter is the GC CPU limiter, or nil if disabled.
+	cpuLimiter *cpuLimiter
+
+	// State
+	gc                      int
+	liveBytesLast           uint64
+	liveScannableLast       uint64
+	allocBlackLast          uint64
+	allocBlackScannableLast uint64
+	consMark                float64
+	lastConsMark            [4]float64
+}
+
+func (s *go118) Step(gc *scenario.Cycle) Result {
+	tr := newTrace(&s.cfg)
+
+	// Simulate up to when GC starts.
+	//
+	// 1. Figure out the goal.
+	// 2. Figure out the trigger.
+	// 3. Figure out the expected scan work.
+
+	heapGoal, hardHeapGoal := uint64(math.MaxUint64), uint64(math.MaxUint64)
+	if !s.gogcOff() {
+		heapGoal = s.liveBytesLast + uint64(float64(s.liveBytesLast+gc.StackBytes+s.GlobalsBytes)*(s.Gamma-1))
+		if target := gc.HeapTargetBytes; target > 0 && heapGoal < uint64(target) {
+			heapGoal = uint64(target)
+		}
+		if heapMinimum := uint64(float64(s.cfg.HeapMinimum) * (s.Gamma - 1)); heapGoal < heapMinimum {
+			heapGoal = heapMinimum
+		}
+		hardHeapGoal = uint64(s.Gamma * float64(heapGoal))
+	}
+	if s.memoryLimit && s.MemoryLimit > 0 {
+		limitGoal := memoryLimitHeapGoal(&s.Globals, gc)
+		tr.record("memory_limit_goal", float64(limitGoal))
+		if limitGoal < s.liveBytesLast {
+			// A heap goal below the live heap doesn't make sense.
+			limitGoal = s.liveBytesLast
+		}
+		if heapGoal > limitGoal {
+			heapGoal = limitGoal
+		}
+		if hardHeapGoal > limitGoal {
+			hardHeapGoal = limitGoal
+		}
+	}
+	expScanWork := s.liveScannableLast + gc.StackBytes + s.GlobalsBytes
+
+	// The trigger is computed from the runway, that is, how many bytes
+	// the mutator will allocate while the GC does the expected scan work
+	// at the goal utilization.
+	//
+	// runway = consMark * (1 - u) / u * expScanWork
+	// triggerPoint = heapGoal - runway
+	//
+	// The trigger is then bounded to between 70% and 95% of the way from
+	// the live heap to the heap goal. For large heaps, the upper bound is
+	// instead the heap goal minus the default heap minimum.
+	var triggerPoint uint64
+	if s.gc == 0 {
+		triggerPoint = 7 * heapGoal / 8
+	} else {
+		minTrigger := uint64(float64(heapGoal-s.liveBytesLast)*s.cfg.MinTriggerRatio) + s.liveBytesLast
+		maxTrigger := uint64(float64(heapGoal-s.liveBytesLast)*s.cfg.MaxTriggerRatio) + s.liveBytesLast
+		if heapGoal > s.cfg.HeapMinimum && heapGoal-s.cfg.HeapMinimum > maxTrigger {
+			maxTrigger = heapGoal - s.cfg.HeapMinimum
+		}
+		if maxTrigger < minTrigger {
+			maxTrigger = minTrigger
+		}
+		runway := uint64(s.consMark * (1 - s.cfg.BackgroundUtilization) / s.cfg.BackgroundUtilization * float64(expScanWork))
+		tr.record("min_trigger", float64(minTrigger))
+		tr.record("max_trigger", float64(maxTrigger))
+		tr.record("runway", float64(runway))
+		if runway > heapGoal {
+			triggerPoint = minTrigger
+		} else {
+			triggerPoint = heapGoal - runway
+		}
+		if triggerPoint < minTrigger {
+			triggerPoint = minTrigger
+		}
+		if triggerPoint > maxTrigger {
+			triggerPoint = maxTrigger
+		}
+	}
+
+	// Simulate during-GC pacing.
+	var totalScanWork uint64
+	if s.gc == 0 {
+		totalScanWork = s.InitialHeap + gc.StackBytes + s.GlobalsBytes
+	} else {
+		totalScanWork = uint64(float64(s.liveScannableLast)*gc.GrowthRate) + gc.StackBytes + s.GlobalsBytes
+	}
+
+	// Assists pace against the heap goal for the expected scan work.
+	// If there's more scan work than expected, the runway is extrapolated
+	// to the actual scan work, up to a hard goal of gamma * heapGoal, or
+	// the memory limit's heap goal, whichever is lower.
+	assistRatio := float64(heapGoal-triggerPoint) / float64(expScanWork)
+	if totalScanWork > expScanWork {
+		extHeapGoal := uint64(assistRatio*float64(totalScanWork)) + triggerPoint
+		if extHeapGoal > hardHeapGoal {
+			extHeapGoal = hardHeapGoal
+		}
+		assistRatio = float64(extHeapGoal-triggerPoint) / float64(totalScanWork)
+		tr.record("ext_goal", float64(extHeapGoal))
+	}
+	tr.record("exp_scan_work", float64(expScanWork))
+	tr.record("total_scan_work", float64(totalScanWork))
+	tr.record("assist_ratio", assistRatio)
+
+	// Rely on the during-GC pacer to work perfectly.
+	u := s.cfg.BackgroundUtilization
+	actualRatio := (gc.AllocRate * (1 - u)) / (gc.ScanRate * u)
+	actualU := u
+	if actualRatio > assistRatio {
+		actualRatio = assistRatio
+		// See go116 for the derivation.
+		actualU = gc.AllocRate / (gc.AllocRate + gc.ScanRate*actualRatio)
+	}
+	var cpuLimited bool
+	if s.cpuLimiter != nil {
+		mutatorTime := float64(triggerPoint-s.liveBytesLast) / gc.AllocRate
+		actualRatio, actualU, cpuLimited = s.cpuLimiter.mark(gc, mutatorTime, totalScanWork, actualU)
+	}
+	peakExtra := uint64(actualRatio * float64(totalScanWork))
+	peakHeap := triggerPoint + peakExtra
+
+	// Simulate GC feedback loop.
+	//
+	// 1. Figure out how much survived this GC.
+	// 2. Measure the cons/mark ratio for this cycle.
+	// 3. Take the max over the last few cycles as the new estimate.
+	// 4. Feed how much data survived to the next cycle.
+
+	heapAllocBlack := peakHeap - triggerPoint
+	heapAllocBlackScannable := uint64(float64(heapAllocBlack) * gc.ScannableFrac)
+
+	var heapSurvived, heapScannableSurvived uint64
+	if s.gc == 0 {
+		heapSurvived = s.InitialHeap + heapAllocBlack
+		heapScannableSurvived = uint64(float64(heapSurvived) * gc.ScannableFrac)
+	} else {
+		heapSurvived = uint64(float64(s.liveBytesLast-s.allocBlackLast)*gc.GrowthRate) + heapAllocBlack
+		heapScannableSurvived = uint64(float64(s.liveScannableLast-s.allocBlackScannableLast)*gc.GrowthRate) + heapAllocBlackScannable
+	}
+
+	thisR := s.consMark * (1 - u) / u
+
+	// If the GC used the whole CPU, the mutator didn't allocate
+	// at all and this cycle tells us nothing about cons/mark.
+	if actualU < 1 {
+		currentConsMark := (float64(peakHeap-triggerPoint) * actualU) / (float64(totalScanWork) * (1 - actualU))
+		s.consMark = currentConsMark
+		for _, c := range s.lastConsMark {
+			if c > s.consMark {
+				s.consMark = c
+			}
+		}
+		copy(s.lastConsMark[:], s.lastConsMark[1:])
+		s.lastConsMark[len(s.lastConsMark)-1] = currentConsMark
+		tr.record("cons_mark_measured", currentConsMark)
+	}
+	tr.record("cons_mark", s.consMark)
+
+	s.liveBytesLast = heapSurvived
+	s.liveScannableLast = heapScannableSurvived
+	s.allocBlackLast = heapAllocBlack
+	s.allocBlackScannableLast = heapAllocBlackScannable
+
+	// Final bookkeeping.
+	s.gc++
+
+	// Return result.
+	return Result{
+		R:                   thisR,
+		LiveBytes:           heapSurvived,
+		LiveScanBytes:       heapScannableSurvived,
+		GoalBytes:           heapGoal,
+		ActualGCUtilization: actualU,
+		TargetGCUtilization: u,
+		TriggerPoint:        triggerPoint,
+		PeakBytes:           peakHeap,
+		CPULimited:          cpuLimited,
+		Trace:               tr,
+	}
+}
+
+func (s *go118) gogcOff() bool {
+	return s.memoryLimit && s.GOGCOff
+}
@@ -0,0 +1,98 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+// loop is a pacer's controller, which a scenario may replace
+// between cycles.
+type loop struct {
+	ctrl controller.Controller
+
+	// swaps are the controllers that replace ctrl, by cycle,
+	// and cycle is the current cycle.
+	swaps map[int]controller.Controller
+	cycle int
+
+	// last is the controller's last output, and track indicates
+	// that the controller was replaced and should take over from it.
+	last  float64
+	track bool
+
+	// signals are the controller's internal signals from this cycle,
+	// if it's a controller.Introspector and it ran.
+	signals map[string]float64
+}
+
+// newLoop creates a loop for ctrl, which may be nil, that swaps in the
+// controllers that e's cycles configure.
+func newLoop(ctrl controller.Controller, e *scenario.Execution) (loop, error) {
+	l := loop{ctrl: ctrl}
+	for i := range e.Cycles {
+		data := e.Cycles[i].Controller
+		if len(data) == 0 {
+			continue
+		}
+		c, err := controller.New(data)
+		if err != nil {
+			return loop{}, fmt.Errorf("controller for cycle %d: %v", i, err)
+		}
+		if l.swaps == nil {
+			l.swaps = make(map[int]controller.Controller)
+		}
+		l.swaps[i] = c
+	}
+	return l, nil
+}
+
+// begin starts a new cycle, replacing the controller if the
+// scenario asks for it.
+func (l *loop) begin() {
+	l.signals = nil
+	if c, ok := l.swaps[l.cycle]; ok {
+		l.ctrl = c
+		l.track = true
+	}
+	l.cycle++
+}
+
+func (l *loop) next(input, setpoint float64) float64 {
+	l.takeOver(input, setpoint)
+	l.last = l.ctrl.Next(input, setpoint)
+	l.introspect()
+	return l.last
+}
+
+// observe is like next, but passes obs to the controller if it's
+// a controller.Observer.
+func (l *loop) observe(obs *controller.Observation) float64 {
+	o, ok := l.ctrl.(controller.Observer)
+	if !ok {
+		return l.next(obs.Input, obs.Setpoint)
+	}
+	l.takeOver(obs.Input, obs.Setpoint)
+	l.last = o.Observe(obs)
+	l.introspect()
+	return l.last
+}
+
+func (l *loop) introspect() {
+	if i, ok := l.ctrl.(controller.Introspector); ok {
+		l.signals = i.Introspect()
+	}
+}
+
+// takeOver initializes a new controller so that its output matches the
+// old controller's last output, if it can be initialized.
+func (l *loop) takeOver(input, setpoint float64) {
+	if !l.track {
+		return
+	}
+	if b, ok := l.ctrl.(controller.Bumpless); ok {
+		b.Track(input, setpoint, l.last)
+	}
+	l.track = false
+}
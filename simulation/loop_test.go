@@ -0,0 +1,82 @@
+package simulation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/mknyszek/pacer-model/controller"
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+func loadScenario(t *testing.T, name string) scenario.Execution {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("..", "data", "scenarios", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e scenario.Execution
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func runR(t *testing.T, e scenario.Execution, ctrl controller.Controller) []float64 {
+	t.Helper()
+	s, err := NewSimulator("go117", &e, ctrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r []float64
+	for i := range e.Cycles {
+		r = append(r, s.Step(&e.Cycles[i]).R)
+	}
+	return r
+}
+
+func TestSlewLimitSettles(t *testing.T) {
+	const rate = 0.1
+	for _, name := range []string{"step-alloc", "heavy-step-alloc"} {
+		t.Run(name, func(t *testing.T) {
+			e := loadScenario(t, name)
+			want := runR(t, e, nil)
+			got := runR(t, e, controller.NewSlewLimit(controller.NewPI(&go117PIConfig), rate))
+			for i := 1; i < len(got); i++ {
+				if d := math.Abs(got[i] - got[i-1]); d > rate+1e-9 {
+					t.Errorf("R changed by %f in cycle %d, want at most %f", d, i, rate)
+				}
+			}
+			n := len(got)
+			if w, g := want[n-1], got[n-1]; math.Abs(g-w) > 1e-3*w {
+				t.Errorf("R settled at %f, want %f", g, w)
+			}
+		})
+	}
+}
+
+func TestNewSimulatorBadController(t *testing.T) {
+	e := loadScenario(t, "step-alloc")
+	e.Cycles[30].Controller = json.RawMessage(`{"type": "pi", "k_p": "x"}`)
+	if _, err := NewSimulator("go117", &e, nil, nil); err == nil {
+		t.Error("expected an error for an invalid controller configuration")
+	}
+}
+
+func TestNewSimulatorNoController(t *testing.T) {
+	for _, name := range []string{"go118", "go119"} {
+		e := loadScenario(t, "step-alloc")
+		if _, err := NewSimulator(name, &e, controller.NewPI(&go117PIConfig), nil); err == nil {
+			t.Errorf("%s: expected an error for a controller", name)
+		}
+		e.Cycles[30].Controller = json.RawMessage(`{"type": "pi", "k_p": 0.5}`)
+		if _, err := NewSimulator(name, &e, nil, nil); err == nil {
+			t.Errorf("%s: expected an error for a controller swap", name)
+		}
+		if _, err := NewSimulator("go117", &e, nil, nil); err != nil {
+			t.Errorf("go117: %v", err)
+		}
+	}
+}
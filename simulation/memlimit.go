@@ -0,0 +1,27 @@
+package simulation
+
+import (
+	"github.com/mknyszek/pacer-model/scenario"
+)
+
+const (
+	// memoryLimitMetadataFrac is the approximate overhead of runtime
+	// metadata (spans, heap bitmaps, etc.) as a fraction of the heap.
+	memoryLimitMetadataFrac = 0.03
+
+	// memoryLimitHeadroom is the fraction of the memory limit's heap
+	// goal that is left as headroom to account for pacing inaccuracies.
+	memoryLimitHeadroom = 0.03
+)
+
+// memoryLimitHeapGoal returns the heap goal implied by the memory limit in
+// g, that is, the memory limit minus non-heap overheads: stacks, globals, and
+// runtime metadata.
+func memoryLimitHeapGoal(g *scenario.Globals, gc *scenario.Cycle) uint64 {
+	nonHeap := gc.StackBytes + g.GlobalsBytes
+	if g.MemoryLimit <= nonHeap {
+		return 0
+	}
+	goal := float64(g.MemoryLimit-nonHeap) / (1 + memoryLimitMetadataFrac)
+	return uint64(goal * (1 - memoryLimitHeadroom))
+}
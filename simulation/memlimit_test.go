@@ -0,0 +1,55 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMemoryLimitBelowLiveHeap(t *testing.T) {
+	for _, limiter := range []bool{false, true} {
+		e := loadScenario(t, "memory-limit")
+		e.Globals.MemoryLimit = 3 << 20
+		cfg, err := DefaultConfig("go119")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.CPULimiter = limiter
+		cfg.CPULimiterCapacity = 1e6
+		cfg.Trace = true
+		s, err := NewSimulator("go119", &e, nil, &cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range e.Cycles {
+			r := s.Step(&e.Cycles[i])
+			floats := map[string]float64{
+				"R":                   r.R,
+				"ActualGCUtilization": r.ActualGCUtilization,
+				"TargetGCUtilization": r.TargetGCUtilization,
+				"RVariance":           r.RVariance,
+			}
+			for name, v := range r.Trace {
+				floats["Trace."+name] = v
+			}
+			for name, v := range floats {
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					t.Errorf("cpu limiter %v, cycle %d: %s = %v", limiter, i, name, v)
+				}
+			}
+			// Heap sizes that underflowed or were converted from NaN
+			// end up close to 1<<64.
+			sizes := map[string]uint64{
+				"LiveBytes":     r.LiveBytes,
+				"LiveScanBytes": r.LiveScanBytes,
+				"GoalBytes":     r.GoalBytes,
+				"TriggerPoint":  r.TriggerPoint,
+				"PeakBytes":     r.PeakBytes,
+			}
+			for name, v := range sizes {
+				if v > 1<<40 {
+					t.Errorf("cpu limiter %v, cycle %d: %s = %d", limiter, i, name, v)
+				}
+			}
+		}
+	}
+}
@@ -12,25 +12,76 @@ type Simulator interface {
 	Step(*scenario.Cycle) Result
 }
 
-type simFactory func(scenario.Globals, controller.Controller) Simulator
+type simFactory func(scenario.Globals, loop, *SimulatorConfig) Simulator
 
 var sims = map[string]simFactory{
-	"go116": func(g scenario.Globals, _ controller.Controller) Simulator {
-		return &go116{Globals: g}
-	},
-	"go117": func(g scenario.Globals, c controller.Controller) Simulator {
-		if c == nil {
-			c = controller.NewPI(&controller.PIConfig{
-				Kp:     0.9,
-				Ti:     1.6,
-				Tt:     1000,
-				Period: 1,
-				Min:    -2,
-				Max:    2,
-			})
+	"go116": func(g scenario.Globals, l loop, cfg *SimulatorConfig) Simulator {
+		if l.ctrl == nil {
+			l.ctrl = controller.NewPI(&go116PIConfig)
 		}
-		return &go117{Globals: g, ctrl: c}
+		return &go116{Globals: g, cfg: *cfg, ctrl: l, estimator: cfg.newEstimator()}
+	},
+	"go117": func(g scenario.Globals, l loop, cfg *SimulatorConfig) Simulator {
+		return newGo117(g, l, cfg)
+	},
+	"go117-discrete": func(g scenario.Globals, l loop, cfg *SimulatorConfig) Simulator {
+		return &go117Discrete{go117: *newGo117(g, l, cfg)}
+	},
+	"go118": func(g scenario.Globals, _ loop, cfg *SimulatorConfig) Simulator {
+		return &go118{Globals: g, cfg: *cfg, cpuLimiter: cfg.newCPULimiter()}
 	},
+	"go119": func(g scenario.Globals, _ loop, cfg *SimulatorConfig) Simulator {
+		return &go118{Globals: g, memoryLimit: true, cfg: *cfg, cpuLimiter: cfg.newCPULimiter()}
+	},
+}
+
+func newGo117(g scenario.Globals, l loop, cfg *SimulatorConfig) *go117 {
+	if l.ctrl == nil {
+		l.ctrl = controller.NewPI(&go117PIConfig)
+	}
+	return &go117{Globals: g, cfg: *cfg, ctrl: l, cpuLimiter: cfg.newCPULimiter(), estimator: cfg.newEstimator()}
+}
+
+var (
+	// A proportional controller.
+	go116PIConfig = controller.PIConfig{
+		Kp:     0.5,
+		Ti:     0,
+		Tt:     0,
+		Period: 1,
+		Min:    -1000,
+		Max:    1000,
+	}
+	go117PIConfig = controller.PIConfig{
+		Kp:     0.9,
+		Ti:     1.6,
+		Tt:     1000,
+		Period: 1,
+		Min:    -2,
+		Max:    2,
+	}
+)
+
+// gogcOffSims are the simulators that model GOGC=off. The rest
+// would silently run with Gamma instead.
+var gogcOffSims = map[string]bool{
+	"go119": true,
+}
+
+var defaultControllers = map[string]*controller.PIConfig{
+	"go116":          &go116PIConfig,
+	"go117":          &go117PIConfig,
+	"go117-discrete": &go117PIConfig,
+}
+
+// DefaultControllerConfig returns the configuration of the named simulator's
+// default controller. It returns an error if the simulator doesn't use one.
+func DefaultControllerConfig(name string) (controller.PIConfig, error) {
+	cfg, ok := defaultControllers[name]
+	if !ok {
+		return controller.PIConfig{}, fmt.Errorf("pacer type %q has no controller", name)
+	}
+	return *cfg, nil
 }
 
 func Simulators() []string {
@@ -42,12 +93,40 @@ func Simulators() []string {
 	return s
 }
 
-func NewSimulator(name string, globals scenario.Globals, ctrl controller.Controller) (Simulator, error) {
+// NewSimulator creates the named simulator for an execution. ctrl and cfg
+// are optional: if nil, the simulator's defaults are used. A non-nil cfg
+// should be derived from DefaultConfig. Simulators without a controller
+// reject ctrl and executions that swap in controllers.
+//
+// The controllers that the execution's cycles swap in are created up front,
+// so the simulator must be stepped through the execution's cycles in order.
+func NewSimulator(name string, e *scenario.Execution, ctrl controller.Controller, cfg *SimulatorConfig) (Simulator, error) {
 	f, ok := sims[name]
 	if !ok {
 		return nil, fmt.Errorf("unknown pacer type %q", name)
 	}
-	return f(globals, ctrl), nil
+	if e.Globals.GOGCOff {
+		if !gogcOffSims[name] {
+			return nil, fmt.Errorf("pacer type %q doesn't support GOGC=off", name)
+		}
+		if e.Globals.MemoryLimit == 0 {
+			return nil, fmt.Errorf("GOGC=off requires a memory limit")
+		}
+	}
+	if cfg == nil {
+		cfg = defaultConfigs[name]
+	}
+	if err := cfg.validate(name); err != nil {
+		return nil, err
+	}
+	l, err := newLoop(ctrl, e)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := defaultControllers[name]; !ok && (l.ctrl != nil || len(l.swaps) != 0) {
+		return nil, fmt.Errorf("pacer type %q has no controller", name)
+	}
+	return f(e.Globals, l, cfg), nil
 }
 
 type Result struct {
@@ -59,4 +138,11 @@ type Result struct {
 	TargetGCUtilization float64 `json:"target_u"`
 	TriggerPoint        uint64  `json:"trigger"`
 	PeakBytes           uint64  `json:"peak"`
+	CPULimited          bool    `json:"cpu_limited"`
+	RVariance           float64 `json:"r_variance,omitempty"`
+	Trace               Trace   `json:"trace,omitempty"`
+
+	// Controller contains the internal signals of the pacer's controller
+	// from this cycle, if it's a controller.Introspector.
+	Controller Trace `json:"controller,omitempty"`
 }
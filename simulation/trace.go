@@ -0,0 +1,32 @@
+package simulation
+
+import (
+	"sort"
+)
+
+// Trace contains named intermediate values computed by a simulator during
+// a single GC cycle. It's only populated if SimulatorConfig.Trace is set.
+type Trace map[string]float64
+
+// Names returns the names of all values in the trace in sorted order.
+func (t Trace) Names() []string {
+	var s []string
+	for name := range t {
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return s
+}
+
+func (t Trace) record(name string, value float64) {
+	if t != nil {
+		t[name] = value
+	}
+}
+
+func newTrace(cfg *SimulatorConfig) Trace {
+	if !cfg.Trace {
+		return nil
+	}
+	return make(Trace)
+}
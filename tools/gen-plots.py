@@ -66,7 +66,30 @@ def oPlot(axs):
     axs.set_xlim(t[0], t[-1])
     axs.grid(True)
 
-fig, axs = plt.subplots(2, 2, figsize=(8, 5.5))
+def ctrlPlot(axs):
+    handles = []
+    for name, label in [('proportional', 'Proportional'), ('integral', 'Integral'), ('derivative', 'Derivative'), ('raw_output', 'Raw output'), ('output', 'Output')]:
+        if 'Controller_'+name in data.dtype.names:
+            p, = axs.plot(t, data['Controller_'+name], label=label)
+            handles.append(p)
+    doLegend(axs, handles=handles)
+    axs.set_xlim(t[0], t[-1])
+    axs.grid(True)
+
+def windupPlot(axs):
+    windupPlot, = axs.plot(t, data['Controller_windup_correction'], label='Windup correction')
+    doLegend(axs, handles=[windupPlot])
+    axs.set_xlim(t[0], t[-1])
+    axs.grid(True)
+
+# Show the controller's internals if it reported them.
+hasCtrl = 'Controller_output' in data.dtype.names
+hasWindup = 'Controller_windup_correction' in data.dtype.names
+
+if hasCtrl:
+    fig, axs = plt.subplots(3, 2, figsize=(8, 8))
+else:
+    fig, axs = plt.subplots(2, 2, figsize=(8, 5.5))
 fig.suptitle(f"GOGC={GOGC}, Globals={globData} {globUnit}")
 fig.text(0.5, 0.04, 'GC cycle', ha='center')
 
@@ -74,6 +97,12 @@ bytesPlot(axs[0][0])
 oPlot(axs[1][0])
 uPlot(axs[0][1])
 rPlot(axs[1][1])
+if hasCtrl:
+    ctrlPlot(axs[2][0])
+    if hasWindup:
+        windupPlot(axs[2][1])
+    else:
+        axs[2][1].set_axis_off()
 
 fig.tight_layout(rect=(0, 0.05, 1, 1))
 
@@ -0,0 +1,37 @@
+import numpy as np
+import matplotlib.pyplot as plt
+from matplotlib.colors import ListedColormap
+import sys
+
+if len(sys.argv) == 1:
+    outFile = None
+elif len(sys.argv) == 2:
+    outFile = sys.argv[1]
+else:
+    print(f"usage: {sys.argv[0]} [output file]")
+    print(f"input and output handled with stdin and stdout")
+    exit(1)
+
+data = np.genfromtxt(sys.stdin, delimiter=',', names=True)
+kp = np.unique(data['Kp'])
+ti = np.unique(data['Ti'])
+
+# Rows are Kp, columns are Ti, in the order pacer-stability emits them.
+stability = data['Stability'].reshape(len(kp), len(ti))
+
+fig, axs = plt.subplots(figsize=(6, 5))
+cmap = ListedColormap(['tab:green', 'tab:orange', 'tab:red'])
+mesh = axs.pcolormesh(ti, kp, stability, cmap=cmap, vmin=-0.5, vmax=2.5, shading='nearest')
+cbar = fig.colorbar(mesh, ticks=[0, 1, 2])
+cbar.ax.set_yticklabels(['Converged', 'Oscillating', 'Diverged'])
+axs.set_xscale('log')
+axs.set_yscale('log')
+axs.set_xlabel('Ti')
+axs.set_ylabel('Kp')
+
+fig.tight_layout()
+
+if outFile is None:
+    plt.show()
+else:
+    plt.savefig(outFile, dpi=144)